@@ -6,17 +6,179 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
 	"time"
 
 	"resmon/pkg/config"
-	P "resmon/pkg/mon/pseudo"
 	X "resmon/pkg/mon/perf"
+	P "resmon/pkg/mon/pseudo"
 	T "resmon/pkg/types"
 )
 
-func getenv(k, def string) string { if v := os.Getenv(k); v != "" { return v }; return def }
+func getenv(k, def string) string {
+	if v := os.Getenv(k); v != "" {
+		return v
+	}
+	return def
+}
+
+// 멀티플렉싱으로 신뢰도가 낮은 샘플 표시
+func lowConf(low bool, conf float64) string {
+	if !low {
+		return ""
+	}
+	return fmt.Sprintf(" [low confidence %.0f%%]", conf*100)
+}
+
+// 집계 라벨/cgroup이 있으면 태그에 붙임 ([PERF] / [PERF socket0] / [PERF cg=/a.slice])
+func perfTag(scope, cgroup string) string {
+	tag := "PERF"
+	if scope != "" && scope != X.AggrSystem {
+		tag += " " + scope
+	}
+	if cgroup != "" {
+		tag += " cg=" + cgroup
+	}
+	return tag
+}
+
+// config → perf 모듈 설정
+func perfConfigFrom(cfg *config.Config, interval time.Duration) X.Config {
+	pc := X.Config{
+		Interval:          interval,
+		Events:            cfg.Monitoring.Perf.Events,
+		Backend:           cfg.Monitoring.Perf.Backend,
+		Aggregation:       cfg.Monitoring.Perf.Aggregation,
+		Cgroups:           cfg.Monitoring.Perf.Cgroups,
+		MinConfidence:     cfg.Monitoring.Perf.MinConfidence,
+		DropLowConfidence: cfg.Monitoring.Perf.DropLowConfidence,
+		TopDown:           cfg.Monitoring.Perf.TopDown,
+	}
+	for _, m := range cfg.Monitoring.Perf.DerivedMetrics {
+		pc.DerivedMetrics = append(pc.DerivedMetrics, X.Metric{Name: m.Name, Expr: m.Expr})
+	}
+	return pc
+}
+
+// 추가 카운터는 0이 아닌 것만 설정 순서대로
+func printNet(n T.NetSample, counters []string) {
+	tag := "NET"
+	if n.Netns != "" {
+		tag += " ns=" + n.Netns
+	}
+	switch {
+	case n.Err != "":
+		fmt.Printf("[%s] %s read error: %s\n", tag, n.Iface, n.Err)
+		return
+	case n.Invalid:
+		fmt.Printf("[%s] %s counters reset, interval skipped\n", tag, n.Iface)
+		return
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s] %s rx=%dB/s tx=%dB/s", tag, n.Iface, n.RxBps, n.TxBps)
+	if n.SpeedMbps > 0 {
+		fmt.Fprintf(&b, " util rx=%.1f%% tx=%.1f%% of %dMb/s", n.RxUtil*100, n.TxUtil*100, n.SpeedMbps)
+	}
+	if n.OperState != "" && n.OperState != "up" && n.OperState != "unknown" {
+		fmt.Fprintf(&b, " [%s]", n.OperState)
+	}
+	for _, c := range counters {
+		if v := n.Rates[c]; v > 0 {
+			fmt.Fprintf(&b, " %s=%.0f/s", c, v)
+		}
+	}
+	fmt.Println(b.String())
+
+	// 큐별은 패킷이 있는 큐만 한 줄에
+	b.Reset()
+	for _, q := range n.Queues {
+		if q.Pps > 0 {
+			fmt.Fprintf(&b, " %s%d=%.0fpps/%.0fB/s", q.Dir, q.Queue, q.Pps, q.Bps)
+		}
+	}
+	if b.Len() > 0 {
+		fmt.Printf("[%s] %s queues%s\n", tag, n.Iface, b.String())
+	}
+}
+
+// 0이 아닌 카운터만, 전부 0이면 출력 안 함
+func printProto(ps T.ProtoSample, counters []string) {
+	if ps.Err != "" {
+		fmt.Println("[PROTO] read error:", ps.Err)
+		return
+	}
+	if len(counters) == 0 {
+		counters = P.DefaultProtoCounters
+	}
+	var b strings.Builder
+	for _, c := range counters {
+		if v := ps.Rates[c]; v > 0 {
+			fmt.Fprintf(&b, " %s=%.0f/s", c, v)
+		}
+	}
+	if b.Len() > 0 {
+		fmt.Printf("[PROTO] retrans=%.2f%%%s\n", ps.RetransRatio*100, b.String())
+	}
+}
+
+func printMemBw(m T.MemBw) {
+	fmt.Printf("[%s] MemBW total=%.0fMB/s (R=%.0f W=%.0f)%s\n", perfTag(m.Scope, ""), m.TotalMBs, m.ReadMBs, m.WriteMBs, lowConf(m.LowConfidence, m.Confidence))
+}
+
+func printLLC(l T.LLCSample) {
+	fmt.Printf("[%s] LLC mpki=%.2f hit=%.2f loads=%d stores=%d%s\n", perfTag(l.Scope, l.Cgroup), l.MPKI, l.HitRate, l.Loads, l.Stores, lowConf(l.LowConfidence, l.Confidence))
+}
+
+func printTopDown(td T.TopDownSample) {
+	fmt.Printf("[%s] TopDown fe=%.2f be=%.2f bad_spec=%.2f retiring=%.2f%s\n", perfTag(td.Scope, ""),
+		td.FrontendBound, td.BackendBound, td.BadSpeculation, td.Retiring, lowConf(td.LowConfidence, td.Confidence))
+}
+
+// perf 수집기 상태 변화 (재시작/실패) 출력
+func printPerfHealth(h X.Health) {
+	fmt.Printf("[PERF] collector %s (restarts=%d)\n", h.State, h.Restarts)
+	if h.State != X.StateRunning && h.LastExit != "" {
+		fmt.Println("[PERF] last exit:", h.LastExit)
+	}
+}
+
+// 빌트인은 LLC/MemBW 줄로 출력되므로 사용자 정의 메트릭만
+func printMetric(ms T.MetricSample) {
+	if !X.IsBuiltinMetric(ms.Name) {
+		fmt.Printf("[%s] metric %s=%.4g%s\n", perfTag(ms.Scope, ms.Cgroup), ms.Name, ms.Value, lowConf(ms.LowConfidence, ms.Confidence))
+	}
+}
+
+// 여러 채널을 하나로. 전부 닫히면 닫힘 (nil 채널은 무시)
+func merge[V any](chans ...<-chan V) <-chan V {
+	out := make(chan V, len(chans))
+	var wg sync.WaitGroup
+	for _, ch := range chans {
+		if ch == nil {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for v := range ch {
+				out <- v
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
 
 func main() {
+	// 서브커맨드: resmon replay <capture>
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		os.Exit(runReplay(os.Args[2:]))
+	}
+
 	// Parse command line flags
 	configPath := flag.String("config", "", "Path to configuration file")
 	flag.Parse()
@@ -52,30 +214,128 @@ func main() {
 	}
 
 	// 1) pseudo-file 모듈 (PSI + NIC)
+	// PSI 지원 점검: 보고서 출력, auto 스코프와 트리거 창 보정에 사용
+	psiReport := P.ProbePSI()
+	fmt.Println(psiReport)
+	if psiReport.Disabled {
+		fmt.Println("PSI is disabled on the kernel command line (psi=0), boot with psi=1 to enable it")
+	}
 	scope := P.PSIScope{
-		Scope:    cfg.PSIScope.Type,
-		CgPath:   cfg.PSIScope.CgroupPath,
-	}
-	
-	psiMemEv, _ := P.SpawnPSIWatcher(ctx, scope, "memory", cfg.Monitoring.PSI.Memory.Kind,
-		cfg.Monitoring.PSI.Memory.ThresholdUs, cfg.Monitoring.PSI.Memory.WindowUs)
-	psiCpuEv, _ := P.SpawnPSIWatcher(ctx, scope, "cpu", cfg.Monitoring.PSI.CPU.Kind,
-		cfg.Monitoring.PSI.CPU.ThresholdUs, cfg.Monitoring.PSI.CPU.WindowUs)
-	psiIoEv, _ := P.SpawnPSIWatcher(ctx, scope, "io", cfg.Monitoring.PSI.IO.Kind,
-		cfg.Monitoring.PSI.IO.ThresholdUs, cfg.Monitoring.PSI.IO.WindowUs)
+		Scope:  cfg.PSIScope.Type,
+		CgPath: cfg.PSIScope.CgroupPath,
+	}
+	if cfg.PSIScope.Type == "auto" {
+		scope = psiReport.Scope
+	}
+	// tree: 트리거는 서브트리 루트 cgroup 에, 하위 cgroup 들은 폴링
+	var psiTree <-chan T.PSIEvent
+	var psiTreeErrs <-chan error
+	if cfg.PSIScope.Type == "tree" {
+		scope.Scope = "cgroup"
+		treeInterval, err := cfg.GetPSITreeInterval()
+		if err != nil {
+			fmt.Printf("Invalid PSI tree interval: %v, using 1s\n", err)
+			treeInterval = time.Second
+		}
+		psiTree, psiTreeErrs, err = P.SpawnPSITreePoller(ctx, P.PSITreeConfig{Root: cfg.PSIScope.CgroupPath, Interval: treeInterval})
+		if err != nil {
+			fmt.Println("PSI tree error:", err)
+		}
+	}
+
+	// 커널이 실제로 노출하는 자원/줄만 트리거 (irq 는 full 만, 커널 설정에 따라 없을 수 있음)
+	psiCaps, err := P.DiscoverPSI(scope)
+	if err != nil {
+		fmt.Println("PSI discovery error:", err)
+	}
+	var psiEvChans []<-chan T.PSIEvent
+	var psiErrChans []<-chan error
+	psiRes := cfg.GetPSIResources()
+	for _, res := range P.PSIResources {
+		// 자원마다 트리거 여러 개 (각각 커널 트리거 fd 하나)
+		for _, tc := range psiRes[res].GetTriggers(res) {
+			if !psiCaps.Has(res, tc.Kind) {
+				fmt.Printf("PSI %s %s not available (kernel exposes: %v), skipping trigger %s\n", res, tc.Kind, psiCaps[res], tc.Name)
+				continue
+			}
+			tr := P.PSITrigger{
+				Name:        tc.Name,
+				Severity:    tc.Severity,
+				Res:         res,
+				Kind:        tc.Kind,
+				ThresholdUs: tc.ThresholdUs,
+				WindowUs:    tc.WindowUs,
+			}
+			// 비특권이면 창은 2s 배수만 됨 → 올리고 임계값도 같은 비율로
+			if fit := psiReport.FitTrigger(tr); fit != tr {
+				fmt.Printf("PSI trigger %s: window %dus not allowed without CAP_SYS_RESOURCE, using %dus/%dus\n",
+					tr.Name, tr.WindowUs, fit.ThresholdUs, fit.WindowUs)
+				tr = fit
+			}
+			ev, errs, err := P.SpawnPSIWatcher(ctx, scope, tr)
+			if err != nil {
+				fmt.Printf("PSI %s watcher error: %v\n", tc.Name, err)
+				continue
+			}
+			psiEvChans = append(psiEvChans, ev)
+			psiErrChans = append(psiErrChans, errs)
+		}
+	}
+	psiEv := merge(psiEvChans...)
+	psiErrs := merge(psiErrChans...)
 	psiMemPoll := P.SpawnPSIPoller(ctx, scope, "memory", psiMemPollInterval)
 
-	netCh, err := P.SpawnNetWatcher(ctx, cfg.Monitoring.Network.Interface, netInterval)
+	netCh, err := P.SpawnNetWatcher(ctx, P.NetConfig{
+		Interfaces:  cfg.GetNetworkInterfaces(),
+		Interval:    netInterval,
+		Backend:     cfg.Monitoring.Network.Backend,
+		Counters:    cfg.Monitoring.Network.Counters,
+		NominalMbps: cfg.Monitoring.Network.NominalMbps,
+		PerQueue:    cfg.Monitoring.Network.PerQueue,
+		Netns:       cfg.Monitoring.Network.Netns,
+		NetnsPID:    cfg.Monitoring.Network.NetnsPID,
+	})
 	if err != nil {
 		fmt.Println("net watcher error:", err)
 	}
 
+	var protoCh <-chan T.ProtoSample
+	if cfg.Monitoring.Protocol.Enabled {
+		protoInterval, err := cfg.GetProtocolInterval()
+		if err != nil {
+			fmt.Printf("Invalid protocol interval: %v, using 1s\n", err)
+			protoInterval = time.Second
+		}
+		protoCh, err = P.SpawnProtoWatcher(ctx, P.ProtoConfig{
+			Interval: protoInterval,
+			Counters: cfg.Monitoring.Protocol.Counters,
+		})
+		if err != nil {
+			fmt.Println("protocol watcher error:", err)
+		}
+	}
+
 	// 2) perf 모듈 (LLC + MemBW)
-	perfConfig := X.Config{
-		Interval: perfInterval,
-		Events:   cfg.Monitoring.Perf.Events,
+	perfConfig := perfConfigFrom(cfg, perfInterval)
+	if perfConfig.Backend != "exec" {
+		if _, missing := X.NewResolver("").Resolve(perfConfig.Events); len(missing) > 0 {
+			fmt.Printf("perf events not available on this machine: %s\n", strings.Join(missing, ","))
+		}
+	}
+	if perfConfig.TopDown {
+		if _, _, err := X.DetectTopDown(""); err != nil {
+			fmt.Println("topdown disabled:", err)
+		}
+	}
+	perfOut, err := X.Spawn(ctx, perfConfig)
+	if err != nil && perfConfig.Backend != "exec" {
+		// native 실패 시 perf stat 으로 폴백
+		fmt.Println("native perf backend error:", err, "- falling back to perf stat")
+		perfConfig.Backend = "exec"
+		perfConfig.Aggregation = X.AggrSystem
+		perfConfig.Cgroups = nil
+		perfOut, err = X.Spawn(ctx, perfConfig)
 	}
-	memCh, llcCh, err := X.SpawnPerfMonitor(ctx, perfConfig)
 	if err != nil {
 		fmt.Println("perf monitor error:", err)
 	}
@@ -90,28 +350,91 @@ func main() {
 	// 샘플 콘솔 출력 (실전에서는 UDP 송신/집계기로 연결)
 	tick := time.NewTicker(metricsInterval)
 	defer tick.Stop()
+	perfState := X.StateRunning // 마지막으로 출력한 perf 수집기 상태
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case e := <-psiMemEv:
-			fmt.Printf("[PSI] mem some avg10=%.2f%%\n", e.Avg10)
-		case e := <-psiCpuEv:
-			fmt.Printf("[PSI] cpu some avg10=%.2f%%\n", e.Avg10)
-		case e := <-psiIoEv:
-			fmt.Printf("[PSI] io full avg10=%.2f%%\n", e.Avg10)
+		// 트리거 감시가 오류로 끝나면 채널이 닫힘 → nil로 바꿔 select 에서 빠짐
+		case e, ok := <-psiEv:
+			if !ok {
+				psiEv = nil
+				continue
+			}
+			switch e.State {
+			case "gone":
+				fmt.Printf("[PSI %s:%s] cgroup gone, waiting for it to come back\n", e.Severity, e.Trigger)
+			case "rearmed":
+				fmt.Printf("[PSI %s:%s] trigger re-armed\n", e.Severity, e.Trigger)
+			default:
+				fmt.Printf("[PSI %s:%s] %s %s avg10=%.2f%%\n", e.Severity, e.Trigger, e.Res, e.Kind, e.Avg10)
+			}
+		case err, ok := <-psiErrs:
+			if !ok {
+				psiErrs = nil
+				continue
+			}
+			fmt.Println("[PSI] watcher error:", err)
+		// 트리 폴러도 오류로 끝나면 채널이 닫힘
+		case e, ok := <-psiTree:
+			if !ok {
+				psiTree = nil
+				continue
+			}
+			// 압박이 있는 cgroup 만
+			if e.Avg10 > 0 {
+				fmt.Printf("[PSI cg=%s] %s %s avg10=%.2f%%\n", e.Cgroup, e.Res, e.Kind, e.Avg10)
+			}
+		case err, ok := <-psiTreeErrs:
+			if !ok {
+				psiTreeErrs = nil
+				continue
+			}
+			fmt.Println("[PSI] tree error:", err)
 		case e := <-psiMemPoll:
-			_ = e // 필요하면 사용 (정규화: e.Avg10/100)
+			// 폴링 간격 동안의 stall 비율 (avg10 보다 빠름), 압박이 있을 때만
+			if e.StallFrac > 0 {
+				fmt.Printf("[PSI poll] %s %s stall=%.2f%%\n", e.Res, e.Kind, e.StallFrac*100)
+			}
 		case n := <-netCh:
-			fmt.Printf("[NET] %s rx=%dB/s tx=%dB/s\n", n.Iface, n.RxBps, n.TxBps)
-		case m := <-memCh:
-			fmt.Printf("[PERF] MemBW total=%.0fMB/s (R=%.0f W=%.0f)\n", m.TotalMBs, m.ReadMBs, m.WriteMBs)
-		case l := <-llcCh:
-			fmt.Printf("[PERF] LLC mpki=%.2f hit=%.2f loads=%d stores=%d\n", l.MPKI, l.HitRate, l.Loads, l.Stores)
+			printNet(n, cfg.Monitoring.Network.Counters)
+		case ps := <-protoCh:
+			printProto(ps, cfg.Monitoring.Protocol.Counters)
+		// perf 수집기가 포기하면 채널이 닫힘 → nil로 바꿔 select 에서 빠짐
+		case m, ok := <-perfOut.Mem:
+			if !ok {
+				perfOut.Mem = nil
+				continue
+			}
+			printMemBw(m)
+		case l, ok := <-perfOut.LLC:
+			if !ok {
+				perfOut.LLC = nil
+				continue
+			}
+			printLLC(l)
+		case ms, ok := <-perfOut.Metrics:
+			if !ok {
+				perfOut.Metrics = nil
+				continue
+			}
+			printMetric(ms)
+		case td, ok := <-perfOut.TopDown:
+			if !ok {
+				perfOut.TopDown = nil
+				continue
+			}
+			printTopDown(td)
 		case <-tick.C:
 			// 주기 스냅샷/스코어링 등을 여기서
 			_ = T.NowMS()
+			if perfOut.Status != nil {
+				if h := perfOut.Status.Health(); h.State != perfState {
+					printPerfHealth(h)
+					perfState = h.State
+				}
+			}
 		}
 	}
 }
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"resmon/pkg/config"
+	X "resmon/pkg/mon/perf"
+)
+
+// resmon replay [-config path] [-format text|json] <capture>
+// 저장된 perf stat -I -x, 캡처(perf 출력을 파일로 받은 것)를 실시간과 같은 파서로 재생
+func runReplay(args []string) int {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	configPath := fs.String("config", "", "Path to configuration file (derived metrics, confidence threshold)")
+	format := fs.String("format", "text", "Output format: text or json")
+	start := fs.String("start", "", "Wall-clock time the capture started (RFC 3339); sample times are relative to the capture start when empty")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: resmon replay [flags] <perf stat -I -x, capture | ->")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 || (*format != "text" && *format != "json") {
+		fs.Usage()
+		return 2
+	}
+
+	var startT time.Time
+	if *start != "" {
+		t, err := time.Parse(time.RFC3339Nano, *start)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "replay: bad -start:", err)
+			return 2
+		}
+		startT = t
+	}
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		cfg = config.GetDefaultConfig()
+	}
+
+	in := os.Stdin
+	if name := fs.Arg(0); name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "replay:", err)
+			return 1
+		}
+		defer f.Close()
+		in = f
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	// 인터벌은 캡처의 타임스탬프에서 구하므로 설정값은 쓰지 않음
+	out, err := X.Replay(ctx, in, perfConfigFrom(cfg, 0), startT)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "replay:", err)
+		return 1
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	// 텍스트 줄 앞에 캡처 시각: -start 가 있으면 벽시계, 없으면 캡처 시작 후 경과
+	stamp := func(ts int64) string {
+		if startT.IsZero() {
+			return fmt.Sprintf("+%.3fs", float64(ts)/1000)
+		}
+		return time.UnixMilli(ts).Format("15:04:05.000")
+	}
+	emit := func(kind string, ts int64, v any, text func()) {
+		if *format == "json" {
+			_ = enc.Encode(struct {
+				Kind   string `json:"kind"`
+				Sample any    `json:"sample"`
+			}{kind, v})
+			return
+		}
+		fmt.Print(stamp(ts), " ")
+		text()
+	}
+
+	// Replay 채널은 버퍼가 없어 한 번에 하나만 보내지므로 select 로 받아도 인터벌 순서 그대로
+	mem, llc, metrics, td := out.Mem, out.LLC, out.Metrics, out.TopDown
+	for mem != nil || llc != nil || metrics != nil || td != nil {
+		select {
+		case m, ok := <-mem:
+			if !ok {
+				mem = nil
+				continue
+			}
+			emit("membw", m.Ts, m, func() { printMemBw(m) })
+		case l, ok := <-llc:
+			if !ok {
+				llc = nil
+				continue
+			}
+			emit("llc", l.Ts, l, func() { printLLC(l) })
+		case ms, ok := <-metrics:
+			if !ok {
+				metrics = nil
+				continue
+			}
+			// 빌트인 메트릭은 텍스트에서 LLC/MemBW 줄로 나옴
+			if *format == "text" && X.IsBuiltinMetric(ms.Name) {
+				continue
+			}
+			emit("metric", ms.Ts, ms, func() { printMetric(ms) })
+		case s, ok := <-td:
+			if !ok {
+				td = nil
+				continue
+			}
+			emit("topdown", s.Ts, s, func() { printTopDown(s) })
+		}
+	}
+	return 0
+}
@@ -0,0 +1,61 @@
+package cgroupfs
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// cgroup2 마운트를 못 찾았을 때 기본값 (순수 v2 호스트)
+const DefaultMount = "/sys/fs/cgroup"
+
+// mountinfo 에서 첫 cgroup2 마운트 지점 ("... mountpoint ... - cgroup2 ...")
+// 하이브리드 호스트에서는 /sys/fs/cgroup/unified 등. 없으면 빈 값
+func Cgroup2Mount() string {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		pre, post, ok := strings.Cut(sc.Text(), " - ")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(pre)
+		if len(fields) < 5 || !strings.HasPrefix(post, "cgroup2 ") {
+			continue
+		}
+		return unescapeMountinfo(fields[4])
+	}
+	return ""
+}
+
+// 상대 cgroup 경로의 기준 디렉터리. cgroup2 마운트, 없으면 DefaultMount
+func Root() string {
+	if m := Cgroup2Mount(); m != "" {
+		return m
+	}
+	return DefaultMount
+}
+
+// mountinfo 경로의 \040 같은 8진 이스케이프
+func unescapeMountinfo(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if v, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(v))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -13,49 +14,136 @@ type Config struct {
 
 // MonitoringConfig contains all monitoring-related settings
 type MonitoringConfig struct {
-	Network NetworkConfig `yaml:"network"`
-	PSI     PSIConfig     `yaml:"psi"`
-	Perf    PerfConfig    `yaml:"perf"`
+	Network  NetworkConfig  `yaml:"network"`
+	Protocol ProtocolConfig `yaml:"protocol"`
+	PSI      PSIConfig      `yaml:"psi"`
+	Perf     PerfConfig     `yaml:"perf"`
 }
 
 // NetworkConfig contains network monitoring settings
 type NetworkConfig struct {
 	Interface string `yaml:"interface"`
-	Interval  string `yaml:"interval"`
+	// Interface names or globs to watch ("eth*"); a leading "!" excludes ("!veth*").
+	// Takes precedence over Interface when set.
+	Interfaces []string `yaml:"interfaces"`
+	Interval   string   `yaml:"interval"`
+	// Counter source for host interfaces: "sysfs" (per-interface statistics files)
+	// or "netlink" (one RTM_GETLINK dump per interval)
+	Backend string `yaml:"backend"`
+	// Extra /sys/class/net/<if>/statistics counters reported as per-second rates
+	Counters []string `yaml:"counters"`
+	// Capacity in Mb/s for devices that report no speed (bonds, veths, tunnels),
+	// keyed by interface name or glob
+	NominalMbps map[string]int64 `yaml:"nominal_mbps"`
+	// Report per-queue packet/byte rates from ethtool driver statistics
+	PerQueue bool `yaml:"per_queue"`
+	// Watch interfaces inside another network namespace: a name under
+	// /var/run/netns (or a namespace file path), or the namespace of a PID
+	Netns    string `yaml:"netns"`
+	NetnsPID int    `yaml:"netns_pid"`
+}
+
+// ProtocolConfig contains TCP/UDP counter monitoring settings
+// (/proc/net/snmp and /proc/net/netstat)
+type ProtocolConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Sampling interval; empty uses the network interval
+	Interval string `yaml:"interval"`
+	// Counters as "Proto.Field" (Tcp.RetransSegs, TcpExt.ListenOverflows, Udp.RcvbufErrors, ...)
+	Counters []string `yaml:"counters"`
 }
 
 // PSIConfig contains PSI monitoring settings
 type PSIConfig struct {
-	Memory           PSIResourceConfig `yaml:"memory"`
-	CPU              PSIResourceConfig `yaml:"cpu"`
-	IO               PSIResourceConfig `yaml:"io"`
-	MemoryPollInterval string          `yaml:"memory_poll_interval"`
+	Memory             PSIResourceConfig `yaml:"memory"`
+	CPU                PSIResourceConfig `yaml:"cpu"`
+	IO                 PSIResourceConfig `yaml:"io"`
+	IRQ                PSIResourceConfig `yaml:"irq"`
+	MemoryPollInterval string            `yaml:"memory_poll_interval"`
 }
 
-// PSIResourceConfig contains settings for a specific PSI resource
+// PSIResourceConfig contains settings for a specific PSI resource.
+// threshold_us/window_us/kind is shorthand for a single trigger; use triggers for several
 type PSIResourceConfig struct {
+	ThresholdUs int                `yaml:"threshold_us"`
+	WindowUs    int                `yaml:"window_us"`
+	Kind        string             `yaml:"kind"` // "some", "full" or empty to not watch the resource
+	Triggers    []PSITriggerConfig `yaml:"triggers"`
+}
+
+// PSITriggerConfig is one kernel trigger on a PSI resource
+type PSITriggerConfig struct {
+	Name        string `yaml:"name"`     // Default: the resource name plus the index
+	Severity    string `yaml:"severity"` // "info", "warn" or "critical" (Default: "warn")
 	ThresholdUs int    `yaml:"threshold_us"`
 	WindowUs    int    `yaml:"window_us"`
 	Kind        string `yaml:"kind"`
 }
 
+// GetTriggers returns the triggers of a resource with names and severities filled in
+func (r PSIResourceConfig) GetTriggers(res string) []PSITriggerConfig {
+	trigs := r.Triggers
+	if len(trigs) == 0 {
+		if r.Kind == "" {
+			return nil
+		}
+		trigs = []PSITriggerConfig{{Name: res, ThresholdUs: r.ThresholdUs, WindowUs: r.WindowUs, Kind: r.Kind}}
+	}
+	out := make([]PSITriggerConfig, len(trigs))
+	for i, t := range trigs {
+		if t.Name == "" {
+			t.Name = fmt.Sprintf("%s-%d", res, i)
+		}
+		if t.Severity == "" {
+			t.Severity = "warn"
+		}
+		out[i] = t
+	}
+	return out
+}
+
 // PerfConfig contains performance monitoring settings
 type PerfConfig struct {
 	Interval string   `yaml:"interval"`
 	Events   []string `yaml:"events"`
+	Backend  string   `yaml:"backend"` // "native" (perf_event_open) or "exec" (perf stat)
+	// Extra breakdown emitted next to the system-wide total:
+	// "system" (total only), "cpu", "socket" or "node"
+	Aggregation string `yaml:"aggregation"`
+	// cgroup v2 paths (absolute, or relative to the cgroup2 mount) whose LLC and
+	// instruction counts are reported separately
+	Cgroups []string `yaml:"cgroups"`
+	// Named expressions over raw event counts, evaluated every interval
+	DerivedMetrics []DerivedMetricConfig `yaml:"derived_metrics"`
+	// Collect top-down level 1 (frontend/backend bound, bad speculation,
+	// retiring); skipped on CPUs without topdown events
+	TopDown bool `yaml:"topdown"`
+	// Samples whose counters ran less than this fraction of the interval
+	// (time_running/time_enabled) are flagged as low confidence
+	MinConfidence     float64 `yaml:"min_confidence"`
+	DropLowConfidence bool    `yaml:"drop_low_confidence"`
+}
+
+// DerivedMetricConfig defines a metric computed from perf event counts,
+// e.g. {name: ipc, expr: "instructions / cycles"}
+type DerivedMetricConfig struct {
+	Name string `yaml:"name"`
+	Expr string `yaml:"expr"`
 }
 
 // OutputConfig contains output-related settings
 type OutputConfig struct {
-	Console        bool   `yaml:"console"`
-	LogLevel       string `yaml:"log_level"`
+	Console         bool   `yaml:"console"`
+	LogLevel        string `yaml:"log_level"`
 	MetricsInterval string `yaml:"metrics_interval"`
 }
 
 // PSIScopeConfig contains PSI scope settings
 type PSIScopeConfig struct {
-	Type       string `yaml:"type"`        // "system" or "cgroup"
+	Type       string `yaml:"type"` // "auto", "system", "cgroup" or "tree"
 	CgroupPath string `yaml:"cgroup_path"`
+	// Poll interval for every cgroup under cgroup_path when type is "tree"
+	TreeInterval string `yaml:"tree_interval"`
 }
 
 // Helper methods to convert string durations to time.Duration
@@ -63,10 +151,44 @@ func (c *Config) GetNetworkInterval() (time.Duration, error) {
 	return time.ParseDuration(c.Monitoring.Network.Interval)
 }
 
+// GetNetworkInterfaces returns the interface patterns to watch
+func (c *Config) GetNetworkInterfaces() []string {
+	if len(c.Monitoring.Network.Interfaces) > 0 {
+		return c.Monitoring.Network.Interfaces
+	}
+	return []string{c.Monitoring.Network.Interface}
+}
+
+// GetProtocolInterval falls back to the network interval when unset
+func (c *Config) GetProtocolInterval() (time.Duration, error) {
+	if c.Monitoring.Protocol.Interval == "" {
+		return c.GetNetworkInterval()
+	}
+	return time.ParseDuration(c.Monitoring.Protocol.Interval)
+}
+
 func (c *Config) GetPSIMemoryPollInterval() (time.Duration, error) {
 	return time.ParseDuration(c.Monitoring.PSI.MemoryPollInterval)
 }
 
+// GetPSIResources returns the trigger config for each PSI resource by name
+func (c *Config) GetPSIResources() map[string]PSIResourceConfig {
+	return map[string]PSIResourceConfig{
+		"memory": c.Monitoring.PSI.Memory,
+		"cpu":    c.Monitoring.PSI.CPU,
+		"io":     c.Monitoring.PSI.IO,
+		"irq":    c.Monitoring.PSI.IRQ,
+	}
+}
+
+// GetPSITreeInterval defaults to 1s when unset
+func (c *Config) GetPSITreeInterval() (time.Duration, error) {
+	if c.PSIScope.TreeInterval == "" {
+		return time.Second, nil
+	}
+	return time.ParseDuration(c.PSIScope.TreeInterval)
+}
+
 func (c *Config) GetPerfInterval() (time.Duration, error) {
 	return time.ParseDuration(c.Monitoring.Perf.Interval)
 }
@@ -4,7 +4,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	
+	"strings"
+
 	"gopkg.in/yaml.v3"
 )
 
@@ -49,14 +50,14 @@ func findConfigFile() string {
 		if _, err := os.Stat(configPath); err == nil {
 			return configPath
 		}
-		
+
 		parent := filepath.Dir(dir)
 		if parent == dir {
 			break // Reached root
 		}
 		dir = parent
 	}
-	
+
 	// Fallback to current directory
 	return "config.yaml"
 }
@@ -64,19 +65,120 @@ func findConfigFile() string {
 // Validate validates the configuration
 func (c *Config) Validate() error {
 	// Validate PSI scope
-	if c.PSIScope.Type != "system" && c.PSIScope.Type != "cgroup" {
-		return fmt.Errorf("invalid PSI scope type: %s (must be 'system' or 'cgroup')", c.PSIScope.Type)
+	switch c.PSIScope.Type {
+	case "auto", "system", "cgroup":
+	case "tree":
+		if c.PSIScope.CgroupPath == "" {
+			return fmt.Errorf("PSI scope type tree requires cgroup_path")
+		}
+	default:
+		return fmt.Errorf("invalid PSI scope type: %s (must be 'auto', 'system', 'cgroup' or 'tree')", c.PSIScope.Type)
+	}
+
+	// Validate PSI triggers. Whether the kernel exposes the line is checked at startup
+	triggerNames := map[string]bool{}
+	for name, resource := range c.GetPSIResources() {
+		if resource.Kind != "" && resource.Kind != "some" && resource.Kind != "full" {
+			return fmt.Errorf("invalid PSI %s kind: %s (must be 'some', 'full' or empty)", name, resource.Kind)
+		}
+		if resource.Kind != "" && len(resource.Triggers) > 0 {
+			return fmt.Errorf("PSI %s: use either kind/threshold_us/window_us or triggers, not both", name)
+		}
+		for _, t := range resource.GetTriggers(name) {
+			if t.Kind != "some" && t.Kind != "full" {
+				return fmt.Errorf("invalid PSI %s trigger %s kind: %s (must be 'some' or 'full')", name, t.Name, t.Kind)
+			}
+			switch t.Severity {
+			case "info", "warn", "critical":
+			default:
+				return fmt.Errorf("invalid PSI %s trigger %s severity: %s (must be 'info', 'warn' or 'critical')", name, t.Name, t.Severity)
+			}
+			if triggerNames[t.Name] {
+				return fmt.Errorf("duplicate PSI trigger name: %s", t.Name)
+			}
+			triggerNames[t.Name] = true
+		}
 	}
 
-	// Validate PSI resource kinds
-	psiResources := []PSIResourceConfig{c.Monitoring.PSI.Memory, c.Monitoring.PSI.CPU, c.Monitoring.PSI.IO}
-	for i, resource := range psiResources {
-		resourceNames := []string{"memory", "cpu", "io"}
-		if resource.Kind != "some" && resource.Kind != "full" {
-			return fmt.Errorf("invalid PSI %s kind: %s (must be 'some' or 'full')", resourceNames[i], resource.Kind)
+	// Validate network interface patterns
+	for _, p := range c.GetNetworkInterfaces() {
+		if _, err := filepath.Match(strings.TrimPrefix(p, "!"), ""); err != nil {
+			return fmt.Errorf("invalid network interface pattern %q: %w", p, err)
 		}
 	}
 
+	for _, name := range c.Monitoring.Network.Counters {
+		if name == "" || strings.ContainsRune(name, '/') {
+			return fmt.Errorf("invalid network counter name %q", name)
+		}
+	}
+
+	for pattern, mbps := range c.Monitoring.Network.NominalMbps {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid nominal_mbps interface pattern %q: %w", pattern, err)
+		}
+		if mbps <= 0 {
+			return fmt.Errorf("invalid nominal_mbps for %s: %d (must be positive)", pattern, mbps)
+		}
+	}
+
+	switch c.Monitoring.Network.Backend {
+	case "", "sysfs", "netlink":
+	default:
+		return fmt.Errorf("invalid network backend: %s (must be 'sysfs' or 'netlink')", c.Monitoring.Network.Backend)
+	}
+	if c.Monitoring.Network.Backend == "netlink" && (c.Monitoring.Network.Netns != "" || c.Monitoring.Network.NetnsPID != 0) {
+		return fmt.Errorf("network netns requires the sysfs backend")
+	}
+	if c.Monitoring.Network.Netns != "" && c.Monitoring.Network.NetnsPID != 0 {
+		return fmt.Errorf("network netns and netns_pid are mutually exclusive")
+	}
+	if c.Monitoring.Network.PerQueue && (c.Monitoring.Network.Netns != "" || c.Monitoring.Network.NetnsPID != 0) {
+		return fmt.Errorf("network per_queue statistics are available for the host namespace only")
+	}
+	if c.Monitoring.Network.NetnsPID < 0 {
+		return fmt.Errorf("invalid network netns_pid: %d", c.Monitoring.Network.NetnsPID)
+	}
+
+	// Validate protocol counter names
+	for _, name := range c.Monitoring.Protocol.Counters {
+		if proto, field, ok := strings.Cut(name, "."); !ok || proto == "" || field == "" {
+			return fmt.Errorf("invalid protocol counter %q (must be Proto.Field, e.g. Tcp.RetransSegs)", name)
+		}
+	}
+
+	// Validate perf backend
+	switch c.Monitoring.Perf.Backend {
+	case "", "native", "exec":
+	default:
+		return fmt.Errorf("invalid perf backend: %s (must be 'native' or 'exec')", c.Monitoring.Perf.Backend)
+	}
+
+	// Validate perf aggregation
+	switch c.Monitoring.Perf.Aggregation {
+	case "", "system", "cpu", "socket", "node":
+	default:
+		return fmt.Errorf("invalid perf aggregation: %s (must be one of: system, cpu, socket, node)", c.Monitoring.Perf.Aggregation)
+	}
+	if c.Monitoring.Perf.Backend == "exec" && c.Monitoring.Perf.Aggregation != "" && c.Monitoring.Perf.Aggregation != "system" {
+		return fmt.Errorf("perf aggregation %s requires the native backend", c.Monitoring.Perf.Aggregation)
+	}
+	if c.Monitoring.Perf.Backend == "exec" && len(c.Monitoring.Perf.Cgroups) > 0 {
+		return fmt.Errorf("per-cgroup perf counting requires the native backend")
+	}
+
+	// Validate derived metric definitions (expressions are parsed by the perf monitor)
+	for i, m := range c.Monitoring.Perf.DerivedMetrics {
+		if m.Name == "" || m.Expr == "" {
+			return fmt.Errorf("invalid perf derived metric #%d: name and expr are required", i+1)
+		}
+	}
+
+	// Validate perf confidence threshold
+	if c.Monitoring.Perf.MinConfidence < 0 || c.Monitoring.Perf.MinConfidence > 1 {
+		return fmt.Errorf("invalid perf min_confidence: %v (must be between 0 and 1)", c.Monitoring.Perf.MinConfidence)
+	}
+
 	// Validate log level
 	validLogLevels := []string{"debug", "info", "warn", "error"}
 	validLevel := false
@@ -100,6 +202,12 @@ func GetDefaultConfig() *Config {
 			Network: NetworkConfig{
 				Interface: "enp4s0",
 				Interval:  "1s",
+				Backend:   "sysfs",
+				Counters:  []string{"rx_packets", "tx_packets", "rx_errors", "rx_dropped", "tx_dropped", "rx_missed_errors"},
+			},
+			Protocol: ProtocolConfig{
+				// Counters left empty: the collector falls back to pseudo.DefaultProtoCounters
+				Enabled: true,
 			},
 			PSI: PSIConfig{
 				Memory: PSIResourceConfig{
@@ -120,7 +228,10 @@ func GetDefaultConfig() *Config {
 				MemoryPollInterval: "1s",
 			},
 			Perf: PerfConfig{
-				Interval: "1s",
+				Interval:      "1s",
+				Backend:       "native",
+				Aggregation:   "system",
+				MinConfidence: 0.5,
 				Events: []string{
 					"LLC-loads",
 					"LLC-load-misses",
@@ -133,12 +244,12 @@ func GetDefaultConfig() *Config {
 			},
 		},
 		Output: OutputConfig{
-			Console:        true,
-			LogLevel:       "info",
+			Console:         true,
+			LogLevel:        "info",
 			MetricsInterval: "1s",
 		},
 		PSIScope: PSIScopeConfig{
-			Type:       "system",
+			Type:       "auto",
 			CgroupPath: "/sys/fs/cgroup",
 		},
 	}
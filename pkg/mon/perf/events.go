@@ -0,0 +1,269 @@
+package perf
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// perf 툴의 JSON 이벤트 별칭 → sysfs 이벤트 이름
+// (perf는 pmu-events 테이블로 해석하지만 sysfs에는 원래 이름만 있음)
+var eventAliases = map[string]string{
+	"unc_m_cas_count_rd":  "cas_count_read",
+	"unc_m_cas_count_wr":  "cas_count_write",
+	"unc_m_cas_count_all": "cas_count_all",
+}
+
+// 해석된 이벤트 하나. uncore 이벤트는 IMC 박스마다 하나씩 생긴다.
+type ResolvedEvent struct {
+	Name string    // 설정에 적힌 이름 (카운터 분류 키)
+	PMU  string    // sysfs PMU 이름, 일반 이벤트면 ""
+	Attr eventAttr // perf_event_attr type/config
+	CPUs []int     // PMU cpumask, nil이면 모든 online CPU
+	// events/<name>.scale 값 (없으면 1). perf stat 은 출력 시 곱해서 보여줌
+	Scale float64
+}
+
+// 코어 PMU 이벤트인지 (CPU별 그룹에 넣을 수 있는지)
+func (e ResolvedEvent) core() bool { return e.CPUs == nil }
+
+// /sys/bus/event_source/devices 기반 이벤트 해석기
+// Root를 바꾸면 가짜 sysfs 트리로 테스트할 수 있음
+type Resolver struct {
+	Root string // 기본 "/sys"
+	// 일반 이벤트(LLC-*, branch-misses 등)는 sysfs 에 없어서 커널이 받아 주는지 열어 봐야 앎
+	// (AMD 의 LLC-loads 는 ENOENT). 오류면 missing. nil이면 안 봄
+	Probe func(ev ResolvedEvent) error
+}
+
+func NewResolver(root string) *Resolver {
+	if root == "" {
+		root = "/sys"
+	}
+	r := &Resolver{Root: root}
+	r.Probe = r.probeEvent
+	return r
+}
+
+func (r *Resolver) devicesDir() string {
+	return filepath.Join(r.Root, "bus/event_source/devices")
+}
+
+// 설정 이벤트 목록 해석. 이 머신에 없는 이벤트는 missing으로 돌려줌
+func (r *Resolver) Resolve(names []string) (resolved []ResolvedEvent, missing []string) {
+	for _, name := range names {
+		evs, err := r.resolveOne(name)
+		// sysfs 이벤트는 열어 보지 않음 (topdown-* 는 slots 리더 그룹 안에서만 열림)
+		if err == nil && r.Probe != nil && len(evs) == 1 && evs[0].PMU == "" {
+			err = r.Probe(evs[0])
+		}
+		if err != nil || len(evs) == 0 {
+			missing = append(missing, name)
+			continue
+		}
+		resolved = append(resolved, evs...)
+	}
+	return resolved, missing
+}
+
+func (r *Resolver) resolveOne(name string) ([]ResolvedEvent, error) {
+	if a, ok := genericEvents[name]; ok {
+		return []ResolvedEvent{{Name: name, Attr: a, Scale: 1}}, nil
+	}
+
+	// pmu/terms/ 형식: uncore_imc_0/cas_count_read/, uncore_imc/event=0x4,umask=0x3/
+	if strings.Count(name, "/") == 2 && strings.HasSuffix(name, "/") {
+		pmu, terms, _ := strings.Cut(strings.TrimSuffix(name, "/"), "/")
+		pmus, err := r.matchPMUs(pmu)
+		if err != nil {
+			return nil, err
+		}
+		var out []ResolvedEvent
+		for _, p := range pmus {
+			ev, err := r.resolveTerms(name, p, terms)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, ev)
+		}
+		return out, nil
+	}
+
+	// 맨 이름: 모든 PMU의 events/ 에서 검색
+	evName := name
+	if a, ok := eventAliases[name]; ok {
+		evName = a
+	}
+	pmus, err := r.listPMUs()
+	if err != nil {
+		return nil, err
+	}
+	var out []ResolvedEvent
+	for _, p := range pmus {
+		if _, err := os.Stat(filepath.Join(r.devicesDir(), p, "events", evName)); err != nil {
+			continue
+		}
+		ev, err := r.resolveTerms(name, p, evName)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, ev)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("event %q not found in sysfs", name)
+	}
+	return out, nil
+}
+
+func (r *Resolver) listPMUs() ([]string, error) {
+	ents, err := os.ReadDir(r.devicesDir())
+	if err != nil {
+		return nil, err
+	}
+	var pmus []string
+	for _, e := range ents {
+		pmus = append(pmus, e.Name())
+	}
+	sort.Strings(pmus)
+	return pmus, nil
+}
+
+// PMU 이름 매칭: 정확히 일치하거나 "uncore_imc" → uncore_imc_0, uncore_imc_1, ...
+func (r *Resolver) matchPMUs(pmu string) ([]string, error) {
+	all, err := r.listPMUs()
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, p := range all {
+		if p == pmu {
+			return []string{p}, nil
+		}
+		if rest, ok := strings.CutPrefix(p, pmu+"_"); ok {
+			if _, err := strconv.Atoi(rest); err == nil {
+				out = append(out, p)
+			}
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("pmu %q not found", pmu)
+	}
+	return out, nil
+}
+
+// terms: sysfs 이벤트 이름 또는 "event=0x4,umask=0x3" 같은 term 목록
+func (r *Resolver) resolveTerms(name, pmu, terms string) (ResolvedEvent, error) {
+	dir := filepath.Join(r.devicesDir(), pmu)
+	typ, err := readTrim(filepath.Join(dir, "type"))
+	if err != nil {
+		return ResolvedEvent{}, err
+	}
+	t, err := strconv.ParseUint(typ, 10, 32)
+	if err != nil {
+		return ResolvedEvent{}, fmt.Errorf("pmu %s: bad type %q", pmu, typ)
+	}
+	ev := ResolvedEvent{Name: name, PMU: pmu, Attr: eventAttr{Type: uint32(t)}, Scale: 1}
+
+	// 이벤트 이름이면 events/<name> 내용으로 치환
+	if !strings.Contains(terms, "=") {
+		s, err := readTrim(filepath.Join(dir, "events", terms))
+		if err != nil {
+			return ResolvedEvent{}, fmt.Errorf("pmu %s: event %q: %w", pmu, terms, err)
+		}
+		if sc, err := readTrim(filepath.Join(dir, "events", terms+".scale")); err == nil {
+			if f, err := strconv.ParseFloat(sc, 64); err == nil {
+				ev.Scale = f
+			}
+		}
+		terms = s
+	}
+	for _, term := range strings.Split(terms, ",") {
+		k, v, hasVal := strings.Cut(strings.TrimSpace(term), "=")
+		if k == "" {
+			continue
+		}
+		val := uint64(1)
+		if hasVal {
+			if val, err = strconv.ParseUint(v, 0, 64); err != nil {
+				return ResolvedEvent{}, fmt.Errorf("pmu %s: term %q: %w", pmu, term, err)
+			}
+		}
+		if err := r.applyFormat(dir, k, val, &ev.Attr); err != nil {
+			return ResolvedEvent{}, err
+		}
+	}
+
+	// uncore PMU는 cpumask에 적힌 CPU(소켓당 하나)에서만 열 수 있음
+	if mask, err := readTrim(filepath.Join(dir, "cpumask")); err == nil {
+		if ev.CPUs, err = parseCPUList(mask); err != nil {
+			return ResolvedEvent{}, err
+		}
+	}
+	return ev, nil
+}
+
+// format/<term> ("config:0-7", "config1:0-15", "config:0-7,32-35")에 맞춰 비트 배치
+func (r *Resolver) applyFormat(dir, term string, val uint64, a *eventAttr) error {
+	switch term {
+	case "config":
+		a.Config = val
+		return nil
+	case "config1":
+		a.Config1 = val
+		return nil
+	case "config2":
+		a.Config2 = val
+		return nil
+	}
+	f, err := readTrim(filepath.Join(dir, "format", term))
+	if err != nil {
+		return fmt.Errorf("%s: unknown format term %q", filepath.Base(dir), term)
+	}
+	field, ranges, ok := strings.Cut(f, ":")
+	if !ok {
+		return fmt.Errorf("%s: bad format %q for %s", filepath.Base(dir), f, term)
+	}
+	var dst *uint64
+	switch field {
+	case "config":
+		dst = &a.Config
+	case "config1":
+		dst = &a.Config1
+	case "config2":
+		dst = &a.Config2
+	default:
+		return fmt.Errorf("%s: unsupported format field %q", filepath.Base(dir), field)
+	}
+	for _, rg := range strings.Split(ranges, ",") {
+		lo, hi, isRange := strings.Cut(rg, "-")
+		l, err := strconv.Atoi(lo)
+		if err != nil {
+			return fmt.Errorf("%s: bad format %q", filepath.Base(dir), f)
+		}
+		h := l
+		if isRange {
+			if h, err = strconv.Atoi(hi); err != nil {
+				return fmt.Errorf("%s: bad format %q", filepath.Base(dir), f)
+			}
+		}
+		width := h - l + 1
+		mask := uint64(1)<<width - 1
+		if width >= 64 {
+			mask = ^uint64(0)
+		}
+		*dst |= (val & mask) << l
+		val >>= width
+	}
+	return nil
+}
+
+func readTrim(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
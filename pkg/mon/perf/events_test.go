@@ -0,0 +1,166 @@
+package perf
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// 가짜 /sys/bus/event_source/devices 트리: IMC 박스 두 개 + 번호 없는 free_running PMU + 쪼개진 format 을 쓰는 PMU
+func fakeSysfs(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	files := map[string]string{}
+	for box, typ := range map[string]string{"uncore_imc_0": "13", "uncore_imc_1": "14"} {
+		files[box+"/type"] = typ
+		files[box+"/cpumask"] = "0,18"
+		files[box+"/format/event"] = "config:0-7"
+		files[box+"/format/umask"] = "config:8-15"
+		files[box+"/events/cas_count_read"] = "event=0x04,umask=0x03"
+		files[box+"/events/cas_count_read.scale"] = "6.103515625e-5"
+		files[box+"/events/cas_count_write"] = "event=0x04,umask=0x0c"
+	}
+	files["uncore_imc_free_running_0/type"] = "15"
+	files["uncore_imc_free_running_0/format/event"] = "config:0-7"
+	files["uncore_imc_free_running_0/format/umask"] = "config:8-15"
+	files["uncore_imc_free_running_0/events/cas_count_read"] = "event=0xff,umask=0x20"
+	files["split/type"] = "20"
+	files["split/format/event"] = "config:0-7,32-35"
+	files["split/format/edge"] = "config1:18"
+	for name, body := range files {
+		path := filepath.Join(root, "bus/event_source/devices", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(body+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func fakeResolver(t *testing.T) *Resolver {
+	r := NewResolver(fakeSysfs(t))
+	r.Probe = nil
+	return r
+}
+
+func pmus(evs []ResolvedEvent) []string {
+	var out []string
+	for _, ev := range evs {
+		out = append(out, ev.PMU)
+	}
+	return out
+}
+
+func TestResolveAlias(t *testing.T) {
+	evs, missing := fakeResolver(t).Resolve([]string{"unc_m_cas_count_rd"})
+	if len(missing) > 0 {
+		t.Fatalf("missing = %v", missing)
+	}
+	want := []ResolvedEvent{
+		{Name: "unc_m_cas_count_rd", PMU: "uncore_imc_0", Attr: eventAttr{Type: 13, Config: 0x0304}, CPUs: []int{0, 18}, Scale: 6.103515625e-5},
+		{Name: "unc_m_cas_count_rd", PMU: "uncore_imc_1", Attr: eventAttr{Type: 14, Config: 0x0304}, CPUs: []int{0, 18}, Scale: 6.103515625e-5},
+		// 맨 이름이라 같은 이벤트가 있는 다른 PMU 도 잡힘 (cpumask, .scale 없음)
+		{Name: "unc_m_cas_count_rd", PMU: "uncore_imc_free_running_0", Attr: eventAttr{Type: 15, Config: 0x20ff}, Scale: 1},
+	}
+	if !reflect.DeepEqual(evs, want) {
+		t.Fatalf("got %+v\nwant %+v", evs, want)
+	}
+}
+
+func TestResolvePMUExpansion(t *testing.T) {
+	r := fakeResolver(t)
+	tests := []struct {
+		name   string
+		pmus   []string
+		config uint64
+		scale  float64 // term 으로 적거나 .scale 이 없으면 1
+	}{
+		// 번호 없는 PMU 이름은 모든 박스로 (uncore_imc_free_running_0 은 빼고)
+		{"uncore_imc/cas_count_write/", []string{"uncore_imc_0", "uncore_imc_1"}, 0x0c04, 1},
+		{"uncore_imc_1/cas_count_read/", []string{"uncore_imc_1"}, 0x0304, 6.103515625e-5},
+		{"uncore_imc/event=0x04,umask=0x03/", []string{"uncore_imc_0", "uncore_imc_1"}, 0x0304, 1},
+	}
+	for _, tt := range tests {
+		evs, missing := r.Resolve([]string{tt.name})
+		if len(missing) > 0 {
+			t.Errorf("%s: missing", tt.name)
+			continue
+		}
+		if got := pmus(evs); !slices.Equal(got, tt.pmus) {
+			t.Errorf("%s: pmus = %v, want %v", tt.name, got, tt.pmus)
+		}
+		for _, ev := range evs {
+			if ev.Attr.Config != tt.config || ev.Scale != tt.scale {
+				t.Errorf("%s on %s: config = %#x scale = %v, want %#x %v", tt.name, ev.PMU, ev.Attr.Config, ev.Scale, tt.config, tt.scale)
+			}
+		}
+	}
+}
+
+func TestApplyFormat(t *testing.T) {
+	r := fakeResolver(t)
+	dir := filepath.Join(r.devicesDir(), "split")
+	tests := []struct {
+		term string
+		val  uint64
+		want eventAttr
+	}{
+		{"event", 0x5a, eventAttr{Config: 0x5a}},
+		// 아래 8비트는 0-7, 그 위 4비트는 32-35
+		{"event", 0xabc, eventAttr{Config: 0xbc | 0xa<<32}},
+		// 범위를 넘는 비트는 버림
+		{"event", 0x1fff, eventAttr{Config: 0xff | 0xf<<32}},
+		{"edge", 1, eventAttr{Config1: 1 << 18}},
+		{"config2", 7, eventAttr{Config2: 7}},
+	}
+	for _, tt := range tests {
+		var a eventAttr
+		if err := r.applyFormat(dir, tt.term, tt.val, &a); err != nil {
+			t.Errorf("%s=%#x: %v", tt.term, tt.val, err)
+			continue
+		}
+		if a != tt.want {
+			t.Errorf("%s=%#x: got %+v, want %+v", tt.term, tt.val, a, tt.want)
+		}
+	}
+	var a eventAttr
+	if err := r.applyFormat(dir, "umask", 1, &a); err == nil {
+		t.Error("unknown format term: want error")
+	}
+}
+
+func TestResolveMissing(t *testing.T) {
+	r := fakeResolver(t)
+	// AMD 처럼 LLC-loads 를 거부하는 커널
+	r.Probe = func(ev ResolvedEvent) error {
+		if ev.Name == "LLC-loads" {
+			return unix.ENOENT
+		}
+		return nil
+	}
+	evs, missing := r.Resolve([]string{
+		"instructions", "LLC-loads", "cas_count_read", "no_such_event",
+		"uncore_imc/no_such_event/", "uncore_cha/event=0x1/", "split/umask=0x1/",
+	})
+	wantMissing := []string{"LLC-loads", "no_such_event", "uncore_imc/no_such_event/", "uncore_cha/event=0x1/", "split/umask=0x1/"}
+	if !slices.Equal(missing, wantMissing) {
+		t.Errorf("missing = %v, want %v", missing, wantMissing)
+	}
+	// 맨 이름은 그 이벤트가 있는 모든 PMU 에서
+	if got, want := pmus(evs), []string{"", "uncore_imc_0", "uncore_imc_1", "uncore_imc_free_running_0"}; !slices.Equal(got, want) {
+		t.Errorf("resolved pmus = %v, want %v", got, want)
+	}
+	if !evs[0].core() || evs[1].core() {
+		t.Errorf("core() = %v, %v; want generic event core and IMC event uncore", evs[0].core(), evs[1].core())
+	}
+	// cpumask 가 없는 PMU 는 모든 CPU
+	if evs[3].CPUs != nil {
+		t.Errorf("%s CPUs = %v, want nil", evs[3].PMU, evs[3].CPUs)
+	}
+}
@@ -0,0 +1,240 @@
+package perf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 파생 메트릭용 산술식: + - * / 괄호, 숫자, 변수
+//
+// 변수 이름에는 '-'가 들어갈 수 있어서(branch-misses, LLC-load-misses) 뺄셈은
+// 양옆에 공백을 둬야 함: "a - b". '/' 등이 들어간 이벤트 이름은 [uncore_imc/cas_count_read/] 처럼 대괄호로 감쌈
+type Expr struct {
+	src  string
+	root node
+	vars []string
+}
+
+type node interface {
+	eval(vars map[string]float64) (float64, error)
+}
+
+type numNode float64
+
+type varNode string
+
+type unaryNode struct{ x node }
+
+type binNode struct {
+	op   byte
+	l, r node
+}
+
+func (n numNode) eval(map[string]float64) (float64, error) { return float64(n), nil }
+
+func (n varNode) eval(vars map[string]float64) (float64, error) {
+	v, ok := vars[string(n)]
+	if !ok {
+		return 0, fmt.Errorf("undefined variable %q", string(n))
+	}
+	return v, nil
+}
+
+func (n unaryNode) eval(vars map[string]float64) (float64, error) {
+	v, err := n.x.eval(vars)
+	return -v, err
+}
+
+func (n binNode) eval(vars map[string]float64) (float64, error) {
+	l, err := n.l.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+	r, err := n.r.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+	switch n.op {
+	case '+':
+		return l + r, nil
+	case '-':
+		return l - r, nil
+	case '*':
+		return l * r, nil
+	default:
+		// 카운터가 0인 틱에서 NaN/Inf 대신 0 (기존 MPKI/hit rate 계산과 동일)
+		if r == 0 {
+			return 0, nil
+		}
+		return l / r, nil
+	}
+}
+
+func ParseExpr(src string) (*Expr, error) {
+	p := &exprParser{src: src}
+	root, err := p.parseSum()
+	if err != nil {
+		return nil, fmt.Errorf("expr %q: %w", src, err)
+	}
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return nil, fmt.Errorf("expr %q: unexpected %q at %d", src, p.src[p.pos], p.pos)
+	}
+	return &Expr{src: src, root: root, vars: p.vars}, nil
+}
+
+func (e *Expr) Eval(vars map[string]float64) (float64, error) { return e.root.eval(vars) }
+
+// 식이 참조하는 변수 이름들 (중복 제거)
+func (e *Expr) Vars() []string { return e.vars }
+
+func (e *Expr) String() string { return e.src }
+
+type exprParser struct {
+	src  string
+	pos  int
+	vars []string
+}
+
+func (p *exprParser) skipSpace() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func (p *exprParser) peek() byte {
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return p.src[p.pos]
+	}
+	return 0
+}
+
+// sum := product (('+'|'-') product)*
+func (p *exprParser) parseSum() (node, error) {
+	l, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for c := p.peek(); c == '+' || c == '-'; c = p.peek() {
+		p.pos++
+		r, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		l = binNode{op: c, l: l, r: r}
+	}
+	return l, nil
+}
+
+// product := unary (('*'|'/') unary)*
+func (p *exprParser) parseProduct() (node, error) {
+	l, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for c := p.peek(); c == '*' || c == '/'; c = p.peek() {
+		p.pos++
+		r, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l = binNode{op: c, l: l, r: r}
+	}
+	return l, nil
+}
+
+// unary := '-' unary | primary
+func (p *exprParser) parseUnary() (node, error) {
+	if p.peek() == '-' {
+		p.pos++
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return unaryNode{x}, nil
+	}
+	return p.parsePrimary()
+}
+
+// primary := number | ident | '[' name ']' | '(' sum ')'
+func (p *exprParser) parsePrimary() (node, error) {
+	c := p.peek()
+	switch {
+	case c == 0:
+		return nil, fmt.Errorf("unexpected end of expression")
+	case c == '(':
+		p.pos++
+		x, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, fmt.Errorf("missing ')' at %d", p.pos)
+		}
+		p.pos++
+		return x, nil
+	case c == '[':
+		end := strings.IndexByte(p.src[p.pos:], ']')
+		if end < 0 {
+			return nil, fmt.Errorf("missing ']' at %d", p.pos)
+		}
+		name := strings.TrimSpace(p.src[p.pos+1 : p.pos+end])
+		p.pos += end + 1
+		return p.addVar(name), nil
+	case c >= '0' && c <= '9' || c == '.':
+		start := p.pos
+		for p.pos < len(p.src) && isNumByte(p.src, p.pos) {
+			p.pos++
+		}
+		v, err := strconv.ParseFloat(p.src[start:p.pos], 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad number %q", p.src[start:p.pos])
+		}
+		return numNode(v), nil
+	case isIdentStart(c):
+		start := p.pos
+		for p.pos < len(p.src) {
+			b := p.src[p.pos]
+			if isIdentStart(b) || b >= '0' && b <= '9' || b == '.' || b == ':' {
+				p.pos++
+				continue
+			}
+			// '-' 다음이 문자면 이름의 일부 (branch-misses)
+			if b == '-' && p.pos+1 < len(p.src) && isIdentStart(p.src[p.pos+1]) {
+				p.pos++
+				continue
+			}
+			break
+		}
+		return p.addVar(p.src[start:p.pos]), nil
+	}
+	return nil, fmt.Errorf("unexpected %q at %d", c, p.pos)
+}
+
+func (p *exprParser) addVar(name string) node {
+	for _, v := range p.vars {
+		if v == name {
+			return varNode(name)
+		}
+	}
+	p.vars = append(p.vars, name)
+	return varNode(name)
+}
+
+func isIdentStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+// 1e-3 같은 지수 표기 포함
+func isNumByte(s string, i int) bool {
+	c := s[i]
+	switch {
+	case c >= '0' && c <= '9' || c == '.' || c == 'e' || c == 'E':
+		return true
+	case (c == '+' || c == '-') && i > 0 && (s[i-1] == 'e' || s[i-1] == 'E'):
+		return true
+	}
+	return false
+}
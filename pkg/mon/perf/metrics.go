@@ -0,0 +1,253 @@
+package perf
+
+import (
+	"fmt"
+
+	T "resmon/pkg/types"
+)
+
+// CAS 1회 = 64B 캐시라인
+const cacheLineBytes = 64.0
+
+// 이름 붙은 파생 메트릭 정의
+// 식에서 쓸 수 있는 변수: 설정 이벤트 이름, 분류 키(llc_loads, llc_load_misses, llc_stores,
+// llc_store_misses, instructions, cas_rd, cas_wr), interval_s, cacheline_bytes, 앞서 정의된 메트릭 이름
+type Metric struct {
+	Name string
+	Expr string
+}
+
+// 기존 LLC/MemBW 계산을 식으로 옮긴 빌트인. 같은 이름으로 사용자 정의하면 덮어씀
+var builtinMetrics = []Metric{
+	{"llc_mpki", "1000 * (llc_load_misses + llc_store_misses) / instructions"},
+	{"llc_hit_rate", "(llc_loads + llc_stores - llc_load_misses - llc_store_misses) / (llc_loads + llc_stores)"},
+	{"mem_read_mbps", "cas_rd * cacheline_bytes / 1048576 / interval_s"},
+	{"mem_write_mbps", "cas_wr * cacheline_bytes / 1048576 / interval_s"},
+	{"mem_total_mbps", "mem_read_mbps + mem_write_mbps"},
+}
+
+func IsBuiltinMetric(name string) bool {
+	for _, m := range builtinMetrics {
+		if m.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+type compiledMetric struct {
+	name string
+	expr *Expr
+}
+
+// 분류 키 전부 (canonicalEvent 결과)
+var canonicalKeys = append(append([]string(nil), llcKeys...), "cas_rd", "cas_wr")
+
+// 식에서 쓸 수 있는 변수 이름 (앞서 정의된 메트릭 이름은 컴파일하면서 추가)
+// 틀린 이름은 매 틱 평가 실패로 조용히 빠지므로 시작 시 거름
+func metricVars(events []string, topdown bool) map[string]bool {
+	known := map[string]bool{"interval_s": true, "cacheline_bytes": true}
+	for _, k := range canonicalKeys {
+		known[k] = true
+	}
+	for _, ev := range events {
+		known[ev] = true
+		if k := canonicalEvent(ev); k != "" {
+			known[k] = true
+		}
+	}
+	if topdown {
+		for _, evs := range topdownEvents {
+			for _, ev := range evs {
+				known[ev] = true
+			}
+		}
+	}
+	return known
+}
+
+// 빌트인 뒤에 사용자 정의를 붙여 평가 순서대로 컴파일
+// known: 식에서 쓸 수 있는 변수 (metricVars)
+func compileMetrics(user []Metric, known map[string]bool) ([]compiledMetric, error) {
+	defs := append([]Metric(nil), builtinMetrics...)
+	for _, u := range user {
+		replaced := false
+		for i := range defs {
+			if defs[i].Name == u.Name {
+				defs[i].Expr = u.Expr
+				replaced = true
+			}
+		}
+		if !replaced {
+			defs = append(defs, u)
+		}
+	}
+	out := make([]compiledMetric, 0, len(defs))
+	for _, d := range defs {
+		if d.Name == "" {
+			return nil, fmt.Errorf("derived metric with empty name")
+		}
+		e, err := ParseExpr(d.Expr)
+		if err != nil {
+			return nil, fmt.Errorf("derived metric %s: %w", d.Name, err)
+		}
+		for _, v := range e.Vars() {
+			if !known[v] {
+				return nil, fmt.Errorf("derived metric %s: unknown variable %q (not a configured event, event key or earlier metric)", d.Name, v)
+			}
+		}
+		known[d.Name] = true
+		out = append(out, compiledMetric{d.Name, e})
+	}
+	return out, nil
+}
+
+// 모니터 출력 채널 묶음
+type Streams struct {
+	Mem     <-chan T.MemBw
+	LLC     <-chan T.LLCSample
+	Metrics <-chan T.MetricSample
+	TopDown <-chan T.TopDownSample
+
+	// 수집기 상태 (exec 백엔드는 perf 재시작/실패를 반영)
+	Status *Status
+}
+
+// 틱 누적값을 평가해서 채널로 내보내는 쪽
+type emitter struct {
+	cfg     Config
+	metrics []compiledMetric
+
+	mem  chan T.MemBw
+	llc  chan T.LLCSample
+	gen  chan T.MetricSample
+	td   chan T.TopDownSample
+	outs Streams
+
+	topdown string // TMA 이벤트 세트, ""면 topdown 샘플 안 만듦
+
+	// nil이면 채널이 가득 찼을 때 드랍(실시간), 아니면 닫힐 때까지 기다리며 전송(재생)
+	done <-chan struct{}
+
+	// 재생: 캡처 경과 초 → 샘플 Ts(ms). nil이면 지금 시각
+	stamp func(sec float64) int64
+}
+
+func send[S any](e *emitter, ch chan S, v S) {
+	if e.done == nil {
+		select {
+		case ch <- v:
+		default:
+		}
+		return
+	}
+	select {
+	case ch <- v:
+	case <-e.done:
+	}
+}
+
+// labels: 인터벌마다 내보낼 라벨(집계/cgroup) 수. 한 인터벌분이 모두 들어갈 만큼 버퍼
+// 0이면 버퍼 없는 채널: 생산자 하나가 차례로 보내므로 받는 쪽도 그 순서 그대로 받음 (재생)
+func newEmitter(cfg Config, labels int) (*emitter, error) {
+	ms, err := compileMetrics(cfg.DerivedMetrics, metricVars(cfg.Events, cfg.TopDown))
+	if err != nil {
+		return nil, err
+	}
+	size := func(n int) int {
+		if labels == 0 {
+			return 0
+		}
+		return n
+	}
+	e := &emitter{
+		cfg:     cfg,
+		metrics: ms,
+		mem:     make(chan T.MemBw, size(8+labels)),
+		llc:     make(chan T.LLCSample, size(8+labels)),
+		gen:     make(chan T.MetricSample, size(8+labels*len(ms))),
+		td:      make(chan T.TopDownSample, size(8+labels)),
+	}
+	e.outs = Streams{Mem: e.mem, LLC: e.llc, Metrics: e.gen, TopDown: e.td}
+	return e, nil
+}
+
+func (e *emitter) close() {
+	close(e.mem)
+	close(e.llc)
+	close(e.gen)
+	close(e.td)
+}
+
+// 누적값으로 메트릭을 평가해 LLC/MemBW/일반 메트릭 샘플 전송
+// scope: 집계 라벨, cgroup: cgroup 모드 카운터면 경로, sec: 인터벌 길이(초)
+// ts: 샘플 시각(ms). 실시간이면 지금, 재생이면 캡처의 인터벌 시각
+// 신뢰도가 cfg.MinConfidence 미만이면 표시하거나 드랍
+func (e *emitter) emit(t *tick, scope, cgroup string, sec float64, ts int64) {
+	if len(t.counts) == 0 {
+		return
+	}
+	now := ts
+	vars := t.vars(sec)
+	vals := map[string]float64{}
+	conf := map[string]float64{}
+	for _, m := range e.metrics {
+		v, err := m.expr.Eval(vars)
+		if err != nil {
+			continue // 이 머신/틱에 없는 이벤트를 참조
+		}
+		vars[m.name], vals[m.name] = v, v
+		conf[m.name] = t.confidence(m.expr.Vars(), conf)
+		ms := T.MetricSample{
+			Name: m.name, Value: v, Scope: scope, Cgroup: cgroup,
+			Confidence: conf[m.name], LowConfidence: conf[m.name] < e.cfg.MinConfidence,
+			Ts: now, Source: "perf",
+		}
+		if !(ms.LowConfidence && e.cfg.DropLowConfidence) {
+			send(e, e.gen, ms)
+		}
+	}
+
+	// LLC 샘플
+	mpki, okM := vals["llc_mpki"]
+	hit, okH := vals["llc_hit_rate"]
+	if t.hasLLC() && okM && okH {
+		c := min(conf["llc_mpki"], conf["llc_hit_rate"])
+		llc := T.LLCSample{
+			MPKI: mpki, HitRate: hit,
+			Loads:  uint64(vars["llc_loads"]),
+			Stores: uint64(vars["llc_stores"]),
+			Misses: uint64(vars["llc_load_misses"] + vars["llc_store_misses"]),
+			Instr:  uint64(vars["instructions"]), Ts: now, Source: "perf", Scope: scope, Cgroup: cgroup,
+			Confidence: c, LowConfidence: c < e.cfg.MinConfidence,
+		}
+		if !(llc.LowConfidence && e.cfg.DropLowConfidence) {
+			send(e, e.llc, llc)
+		}
+	}
+
+	// TMA level 1 샘플
+	if td, ok := t.topdown(e.topdown); ok {
+		td.Scope, td.Ts, td.Source = scope, now, "perf"
+		td.LowConfidence = td.Confidence < e.cfg.MinConfidence
+		if !(td.LowConfidence && e.cfg.DropLowConfidence) {
+			send(e, e.td, td)
+		}
+	}
+
+	// MemBW 샘플
+	rd, okR := vals["mem_read_mbps"]
+	wr, okW := vals["mem_write_mbps"]
+	tot, okT := vals["mem_total_mbps"]
+	if okR && okW && okT && sec > 0 {
+		c := min(conf["mem_read_mbps"], conf["mem_write_mbps"])
+		mb := T.MemBw{
+			Source: "perf", ReadMBs: rd, WriteMBs: wr,
+			TotalMBs: tot, Ts: now, Scope: scope,
+			Confidence: c, LowConfidence: c < e.cfg.MinConfidence,
+		}
+		if !(mb.LowConfidence && e.cfg.DropLowConfidence) {
+			send(e, e.mem, mb)
+		}
+	}
+}
@@ -0,0 +1,424 @@
+package perf
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+
+	"resmon/pkg/cgroupfs"
+)
+
+// perf_event_attr 의 type/config 필드
+type eventAttr struct {
+	Type    uint32
+	Config  uint64
+	Config1 uint64
+	Config2 uint64
+}
+
+func hw(config uint64) eventAttr {
+	return eventAttr{Type: unix.PERF_TYPE_HARDWARE, Config: config}
+}
+
+func hwCache(cache, op, result uint64) eventAttr {
+	return eventAttr{Type: unix.PERF_TYPE_HW_CACHE, Config: cache | op<<8 | result<<16}
+}
+
+// perf 툴의 일반 이벤트 이름 → attr
+var genericEvents = map[string]eventAttr{
+	"cycles":              hw(unix.PERF_COUNT_HW_CPU_CYCLES),
+	"cpu-cycles":          hw(unix.PERF_COUNT_HW_CPU_CYCLES),
+	"instructions":        hw(unix.PERF_COUNT_HW_INSTRUCTIONS),
+	"cache-references":    hw(unix.PERF_COUNT_HW_CACHE_REFERENCES),
+	"cache-misses":        hw(unix.PERF_COUNT_HW_CACHE_MISSES),
+	"branches":            hw(unix.PERF_COUNT_HW_BRANCH_INSTRUCTIONS),
+	"branch-instructions": hw(unix.PERF_COUNT_HW_BRANCH_INSTRUCTIONS),
+	"branch-misses":       hw(unix.PERF_COUNT_HW_BRANCH_MISSES),
+	"bus-cycles":          hw(unix.PERF_COUNT_HW_BUS_CYCLES),
+	"ref-cycles":          hw(unix.PERF_COUNT_HW_REF_CPU_CYCLES),
+
+	"LLC-loads":        hwCache(unix.PERF_COUNT_HW_CACHE_LL, unix.PERF_COUNT_HW_CACHE_OP_READ, unix.PERF_COUNT_HW_CACHE_RESULT_ACCESS),
+	"LLC-load-misses":  hwCache(unix.PERF_COUNT_HW_CACHE_LL, unix.PERF_COUNT_HW_CACHE_OP_READ, unix.PERF_COUNT_HW_CACHE_RESULT_MISS),
+	"LLC-stores":       hwCache(unix.PERF_COUNT_HW_CACHE_LL, unix.PERF_COUNT_HW_CACHE_OP_WRITE, unix.PERF_COUNT_HW_CACHE_RESULT_ACCESS),
+	"LLC-store-misses": hwCache(unix.PERF_COUNT_HW_CACHE_LL, unix.PERF_COUNT_HW_CACHE_OP_WRITE, unix.PERF_COUNT_HW_CACHE_RESULT_MISS),
+}
+
+// CPU 하나에 묶인 카운터 그룹 (fds[0]이 리더)
+type counterGroup struct {
+	cpu    int
+	uncore bool
+	cgroup string    // cgroup 모드면 설정된 cgroup 경로
+	scale  []float64 // 이벤트별 sysfs .scale (nil이면 1)
+	fds    []int
+	names  []string
+	prev   []uint64
+	buf    []byte
+
+	prevEna, prevRun uint64
+}
+
+// pid/flags: 시스템 전체면 -1/0, cgroup이면 cgroup 디렉터리 fd/PERF_FLAG_PID_CGROUP
+func openGroup(cpu, pid, flags int, names []string, attrs []eventAttr) (*counterGroup, error) {
+	g := &counterGroup{cpu: cpu, names: names, prev: make([]uint64, len(names))}
+	leader := -1
+	for i, a := range attrs {
+		attr := unix.PerfEventAttr{
+			Type:        a.Type,
+			Size:        uint32(unsafe.Sizeof(unix.PerfEventAttr{})),
+			Config:      a.Config,
+			Ext1:        a.Config1,
+			Ext2:        a.Config2,
+			Read_format: unix.PERF_FORMAT_GROUP | unix.PERF_FORMAT_TOTAL_TIME_ENABLED | unix.PERF_FORMAT_TOTAL_TIME_RUNNING,
+		}
+		if i == 0 {
+			attr.Bits = unix.PerfBitDisabled
+		}
+		fd, err := unix.PerfEventOpen(&attr, pid, cpu, leader, flags|unix.PERF_FLAG_FD_CLOEXEC)
+		if err != nil {
+			g.close()
+			return nil, fmt.Errorf("perf_event_open %s on cpu%d: %w", names[i], cpu, err)
+		}
+		if i == 0 {
+			leader = fd
+		}
+		g.fds = append(g.fds, fd)
+	}
+	// read 포맷: nr, time_enabled, time_running, value[nr]
+	g.buf = make([]byte, 8*(3+len(names)))
+	return g, nil
+}
+
+// 이벤트를 첫 online CPU에서 열었다 닫아 봄
+// 권한 부족(EACCES/EPERM)은 이벤트 탓이 아니므로 통과시키고 실제로 열 때 오류를 냄
+func (r *Resolver) probeEvent(ev ResolvedEvent) error {
+	cpu := 0
+	if cpus, err := onlineCPUs(r.Root); err == nil && len(cpus) > 0 {
+		cpu = cpus[0]
+	}
+	g, err := openGroup(cpu, -1, 0, []string{ev.Name}, []eventAttr{ev.Attr})
+	if errors.Is(err, unix.EACCES) || errors.Is(err, unix.EPERM) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	g.close()
+	return nil
+}
+
+// cgroup v2 경로 하나에 대해 CPU마다 그룹을 엶
+// 상대 경로는 cgroup2 마운트 기준 (하이브리드 호스트면 /sys/fs/cgroup/unified). 커널이 열 때 cgroup 참조를 잡으므로 디렉터리 fd는 바로 닫음
+func openCgroupGroups(cg string, cpus []int, names []string, attrs []eventAttr) ([]*counterGroup, error) {
+	path := cg
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(cgroupfs.Root(), path)
+	}
+	cgfd, err := unix.Open(path, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("open cgroup %s: %w", path, err)
+	}
+	defer unix.Close(cgfd)
+
+	var out []*counterGroup
+	for _, cpu := range cpus {
+		g, err := openGroup(cpu, cgfd, unix.PERF_FLAG_PID_CGROUP, names, attrs)
+		if err != nil {
+			for _, o := range out {
+				o.close()
+			}
+			return nil, fmt.Errorf("cgroup %s: %w", cg, err)
+		}
+		g.cgroup = cg
+		out = append(out, g)
+	}
+	return out, nil
+}
+
+func (g *counterGroup) enable() error {
+	return unix.IoctlSetInt(g.fds[0], unix.PERF_EVENT_IOC_ENABLE, unix.PERF_IOC_FLAG_GROUP)
+}
+
+// 이전 read 이후 증분 반환. 멀티플렉싱된 구간은 enabled/running 비율로 스케일하고
+// ratio(running/enabled)를 함께 돌려줌
+func (g *counterGroup) read() (delta []float64, ratio float64, err error) {
+	n, err := unix.Read(g.fds[0], g.buf)
+	if err != nil {
+		return nil, 0, err
+	}
+	if n < len(g.buf) {
+		return nil, 0, fmt.Errorf("short perf read on cpu%d: %d bytes", g.cpu, n)
+	}
+	ena := binary.NativeEndian.Uint64(g.buf[8:])
+	run := binary.NativeEndian.Uint64(g.buf[16:])
+	dEna, dRun := ena-g.prevEna, run-g.prevRun
+	g.prevEna, g.prevRun = ena, run
+
+	scale := 0.0
+	if dRun > 0 {
+		scale = float64(dEna) / float64(dRun)
+		ratio = float64(dRun) / float64(dEna)
+	}
+	delta = make([]float64, len(g.names))
+	for i := range g.names {
+		v := binary.NativeEndian.Uint64(g.buf[8*(3+i):])
+		delta[i] = float64(v-g.prev[i]) * scale
+		if g.scale != nil {
+			delta[i] *= g.scale[i]
+		}
+		g.prev[i] = v
+	}
+	return delta, ratio, nil
+}
+
+func (g *counterGroup) close() {
+	for _, fd := range g.fds {
+		_ = unix.Close(fd)
+	}
+	g.fds = nil
+}
+
+// "0-3,8,10-11" 형식의 CPU 리스트 파싱
+func parseCPUList(s string) ([]int, error) {
+	var cpus []int
+	for _, part := range strings.Split(strings.TrimSpace(s), ",") {
+		if part == "" {
+			continue
+		}
+		lo, hi, isRange := strings.Cut(part, "-")
+		a, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, fmt.Errorf("bad cpu list %q: %w", s, err)
+		}
+		b := a
+		if isRange {
+			if b, err = strconv.Atoi(hi); err != nil {
+				return nil, fmt.Errorf("bad cpu list %q: %w", s, err)
+			}
+		}
+		for c := a; c <= b; c++ {
+			cpus = append(cpus, c)
+		}
+	}
+	return cpus, nil
+}
+
+func onlineCPUs(sysRoot string) ([]int, error) {
+	b, err := os.ReadFile(filepath.Join(sysRoot, "devices/system/cpu/online"))
+	if err != nil {
+		return nil, err
+	}
+	return parseCPUList(string(b))
+}
+
+// perf 바이너리 없이 perf_event_open으로 직접 카운팅
+// 코어 이벤트는 CPU마다 한 그룹, uncore 이벤트는 PMU cpumask CPU에서 박스별로 열고
+// 인터벌마다 읽어서 exec 백엔드와 같은 채널로 내보냄. 이 머신에 없는 이벤트는 건너뜀
+func SpawnNativeMonitor(ctx context.Context, cfg Config) (Streams, error) {
+	res := NewResolver(cfg.SysfsRoot)
+	events, _ := res.Resolve(cfg.Events)
+	if len(events) == 0 {
+		return Streams{}, fmt.Errorf("native perf backend: none of the configured events are available")
+	}
+
+	var coreNames []string
+	var coreAttrs []eventAttr
+	for _, ev := range events {
+		if ev.core() {
+			coreNames = append(coreNames, ev.Name)
+			coreAttrs = append(coreAttrs, ev.Attr)
+		}
+	}
+
+	var groups, cgGroups []*counterGroup
+	closeAll := func() {
+		for _, g := range groups {
+			g.close()
+		}
+		for _, g := range cgGroups {
+			g.close()
+		}
+	}
+	// topdown: 지원 안 하는 CPU면 이 수집만 끔
+	var tdMode string
+	var tdNames []string
+	var tdAttrs []eventAttr
+	var tdScale []float64
+	if cfg.TopDown {
+		if mode, names, err := DetectTopDown(res.Root); err == nil {
+			evs, _ := res.Resolve(names)
+			tdMode = mode
+			for _, ev := range evs {
+				tdNames = append(tdNames, ev.Name)
+				tdAttrs = append(tdAttrs, ev.Attr)
+				tdScale = append(tdScale, ev.Scale)
+			}
+		}
+	}
+
+	var cpus []int
+	if len(coreNames) > 0 || tdMode != "" {
+		var err error
+		if cpus, err = onlineCPUs(res.Root); err != nil {
+			return Streams{}, err
+		}
+	}
+	if len(coreNames) > 0 {
+		for _, cpu := range cpus {
+			g, err := openGroup(cpu, -1, 0, coreNames, coreAttrs)
+			if err != nil {
+				closeAll()
+				return Streams{}, err
+			}
+			groups = append(groups, g)
+		}
+	}
+	// topdown 은 리더(slots/total-slots)가 따로 있는 별도 그룹
+	// legacy 세트의 topdown-total-slots 는 SMT 보정용 .scale 을 곱해야 함
+	if tdMode != "" {
+		for _, cpu := range cpus {
+			g, err := openGroup(cpu, -1, 0, tdNames, tdAttrs)
+			if err != nil {
+				closeAll()
+				return Streams{}, err
+			}
+			g.scale = tdScale
+			groups = append(groups, g)
+		}
+	}
+	// uncore: 코어 이벤트와 같은 그룹에 넣을 수 없어 단독 그룹으로
+	for _, ev := range events {
+		if ev.core() {
+			continue
+		}
+		for _, cpu := range ev.CPUs {
+			g, err := openGroup(cpu, -1, 0, []string{ev.Name}, []eventAttr{ev.Attr})
+			if err != nil {
+				closeAll()
+				return Streams{}, err
+			}
+			g.uncore = true
+			groups = append(groups, g)
+		}
+	}
+	// cgroup별 코어 이벤트 (LLC/instructions). uncore는 cgroup 단위 카운팅 불가
+	if len(cfg.Cgroups) > 0 && len(coreNames) == 0 {
+		closeAll()
+		return Streams{}, fmt.Errorf("native perf backend: cgroup counting needs core events (LLC-*, instructions)")
+	}
+	for _, cg := range cfg.Cgroups {
+		opened, err := openCgroupGroups(cg, cpus, coreNames, coreAttrs)
+		if err != nil {
+			closeAll()
+			return Streams{}, err
+		}
+		cgGroups = append(cgGroups, opened...)
+	}
+	for _, g := range append(groups, cgGroups...) {
+		if err := g.enable(); err != nil {
+			closeAll()
+			return Streams{}, err
+		}
+	}
+
+	// 그룹별 집계 라벨
+	labels := make([]string, len(groups))
+	var scopes []string
+	if cfg.Aggregation != "" && cfg.Aggregation != AggrSystem {
+		var cpus []int
+		for _, g := range groups {
+			cpus = append(cpus, g.cpu)
+		}
+		tp, err := loadTopology(res.Root, cpus)
+		if err != nil {
+			closeAll()
+			return Streams{}, err
+		}
+		seen := map[string]bool{}
+		for i, g := range groups {
+			labels[i] = tp.label(cfg.Aggregation, g.cpu, g.uncore)
+			if !seen[labels[i]] {
+				seen[labels[i]] = true
+				scopes = append(scopes, labels[i])
+			}
+		}
+		sort.Strings(scopes)
+	}
+
+	// 전체 합산 + 라벨/cgroup별 샘플이 한 인터벌에 모두 들어갈 만큼 버퍼
+	em, err := newEmitter(cfg, 1+len(scopes)+len(cfg.Cgroups))
+	if err != nil {
+		closeAll()
+		return Streams{}, err
+	}
+	em.topdown = tdMode
+	go func() {
+		defer em.close()
+		defer closeAll()
+
+		tk := time.NewTicker(cfg.Interval)
+		defer tk.Stop()
+		prevT := time.Now()
+		var total tick
+		per := map[string]*tick{}
+		for _, s := range scopes {
+			per[s] = &tick{}
+		}
+		perCg := map[string]*tick{}
+		for _, cg := range cfg.Cgroups {
+			perCg[cg] = &tick{}
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-tk.C:
+				for gi, g := range groups {
+					delta, ratio, err := g.read()
+					if err != nil {
+						continue
+					}
+					for i, name := range g.names {
+						total.add(name, delta[i], ratio)
+						if t := per[labels[gi]]; t != nil {
+							t.add(name, delta[i], ratio)
+						}
+					}
+				}
+				for _, g := range cgGroups {
+					delta, ratio, err := g.read()
+					if err != nil {
+						continue
+					}
+					for i, name := range g.names {
+						perCg[g.cgroup].add(name, delta[i], ratio)
+					}
+				}
+				sec := now.Sub(prevT).Seconds()
+				em.emit(&total, AggrSystem, "", sec, now.UnixMilli())
+				total.reset()
+				for _, s := range scopes {
+					em.emit(per[s], s, "", sec, now.UnixMilli())
+					per[s].reset()
+				}
+				for _, cg := range cfg.Cgroups {
+					em.emit(perCg[cg], AggrSystem, cg, sec, now.UnixMilli())
+					perCg[cg].reset()
+				}
+				prevT = now
+			}
+		}
+	}()
+	outs := em.outs
+	outs.Status = newStatus()
+	return outs, nil
+}
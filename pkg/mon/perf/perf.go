@@ -3,7 +3,9 @@ package perf
 import (
 	"bufio"
 	"context"
-	"os/exec"
+	"fmt"
+	"io"
+	"math"
 	"strconv"
 	"strings"
 	"time"
@@ -12,8 +14,27 @@ import (
 )
 
 type Config struct {
-	Interval time.Duration
-	Events   []string // perf 이벤트 이름들
+	Interval  time.Duration
+	Events    []string // perf 이벤트 이름들
+	Backend   string   // "native"(perf_event_open) | "exec"(perf stat)
+	SysfsRoot string   // PMU/토폴로지 탐색 루트 (기본 "/sys")
+
+	// 전체 합산 외 추가 집계 단위: system|cpu|socket|node (native 백엔드 전용)
+	Aggregation string
+
+	// 코어 이벤트를 cgroup별로도 카운팅할 cgroup v2 경로들 (native 백엔드 전용)
+	Cgroups []string
+
+	// 빌트인(llc_mpki, mem_read_mbps, ...)에 더해 평가할 파생 메트릭
+	DerivedMetrics []Metric
+
+	// TMA level 1 (frontend/backend bound, bad speculation, retiring) 수집
+	// 지원 안 하는 CPU면 조용히 끔 (DetectTopDown 으로 이유 확인)
+	TopDown bool
+
+	// 멀티플렉싱 신뢰도(running/enabled) 하한. 미만이면 LowConfidence 표시
+	MinConfidence     float64
+	DropLowConfidence bool // true면 표시 대신 드랍
 }
 
 // 기본 이벤트(LLC+메모리 BW)
@@ -32,128 +53,150 @@ func DefaultConfig(interval time.Duration) Config {
 	}
 }
 
+// 설정된 백엔드로 모니터 시작 (기본 native)
+func Spawn(ctx context.Context, cfg Config) (Streams, error) {
+	if cfg.Backend == "exec" {
+		return SpawnPerfMonitor(ctx, cfg)
+	}
+	return SpawnNativeMonitor(ctx, cfg)
+}
+
 // perf 한 프로세스로 LLC + MemBW 동시 파싱
-// 반환: membw 채널, llc 채널
-func SpawnPerfMonitor(ctx context.Context, cfg Config) (<-chan T.MemBw, <-chan T.LLCSample, error) {
-	memCh := make(chan T.MemBw, 8)
-	llcCh := make(chan T.LLCSample, 8)
+func SpawnPerfMonitor(ctx context.Context, cfg Config) (Streams, error) {
+	if cfg.Aggregation != "" && cfg.Aggregation != AggrSystem {
+		return Streams{}, fmt.Errorf("perf stat backend supports only system aggregation, got %q", cfg.Aggregation)
+	}
+	if len(cfg.Cgroups) > 0 {
+		return Streams{}, fmt.Errorf("perf stat backend does not support per-cgroup counting")
+	}
+	em, err := newEmitter(cfg, 1)
+	if err != nil {
+		return Streams{}, err
+	}
 
 	args := []string{
 		"stat", "-a",
 		"-I", strconv.Itoa(int(cfg.Interval / time.Millisecond)),
 		"-x", ",",
 		"-e", strings.Join(cfg.Events, ","),
-		"--", "sleep", "1000000",
 	}
+	if cfg.TopDown {
+		if mode, evs, err := DetectTopDown(cfg.SysfsRoot); err == nil {
+			args = append(args, "-e", topdownGroupArg(evs))
+			em.topdown = mode
+		}
+	}
+	args = append(args, "--", "sleep", "1000000")
 
-	cmd := exec.CommandContext(ctx, "perf", args...)
-	stdout, err := cmd.StderrPipe() // perf는 stderr에 출력
+	st, err := superviseStat(ctx, args, em)
 	if err != nil {
-		return nil, nil, err
-	}
-	if err := cmd.Start(); err != nil {
-		return nil, nil, err
+		return Streams{}, err
 	}
+	outs := em.outs
+	outs.Status = st
+	return outs, nil
+}
 
+// 저장된 perf stat -I -x, 캡처를 같은 파서로 재생
+// 실시간과 달리 소비자가 읽을 때까지 기다리며(드랍 없음), 입력 끝에서 채널을 닫음
+// 샘플 Ts 는 start + 캡처의 인터벌 시각(perf 시작 후 경과 초). start 가 0 이면 캡처 시작 기준 ms
+func Replay(ctx context.Context, r io.Reader, cfg Config, start time.Time) (Streams, error) {
+	// 버퍼 없는 채널: 인터벌 순서(그 안에서는 메트릭, LLC, topdown, MemBW 순)대로 받게 됨
+	em, err := newEmitter(cfg, 0)
+	if err != nil {
+		return Streams{}, err
+	}
+	em.done = ctx.Done()
+	if em.done == nil {
+		// Background 처럼 끝나지 않는 ctx 도 드랍 모드가 되면 안 됨
+		em.done = make(chan struct{})
+	}
+	var base int64
+	if !start.IsZero() {
+		base = start.UnixMilli()
+	}
+	em.stamp = func(sec float64) int64 { return base + int64(math.Round(sec*1000)) }
+	// 캡처에 topdown 이벤트가 있으면 그 세트로 계산 (재생하는 머신의 CPU와 무관)
+	em.topdown = topdownAuto
 	go func() {
-		defer close(memCh)
-		defer close(llcCh)
-		defer func() { _ = cmd.Process.Kill() }()
-
-		sc := bufio.NewScanner(stdout)
-
-		// 현재 틱 누적 변수
-		var loads, lmiss, stores, smiss, instr uint64
-		var haveL, haveLM, haveS, haveSM, haveI bool
-		var rd, wr float64
-		var haveRD, haveWR bool
-		sec := float64(cfg.Interval) / float64(time.Second)
-
-		reset := func() {
-			loads, lmiss, stores, smiss, instr = 0, 0, 0, 0, 0
-			haveL, haveLM, haveS, haveSM, haveI = false, false, false, false, false
-			rd, wr = 0, 0
-			haveRD, haveWR = false, false
+		defer em.close()
+		parseStat(r, em, nil)
+	}()
+	outs := em.outs
+	outs.Status = newStatus()
+	return outs, nil
+}
+
+// perf stat -I -x, 출력 파서. 인터벌이 끝날 때마다 em으로 내보냄
+// diag: CSV가 아닌 줄(perf 에러 메시지 등)을 받을 콜백, nil이면 버림
+func parseStat(r io.Reader, em *emitter, diag func(string)) {
+	sc := bufio.NewScanner(r)
+
+	// 현재 틱 누적 변수
+	// -I 출력의 cols[0]은 perf 시작 후 경과 초. 같은 값을 가진 줄들이 한 인터벌
+	var t tick
+	var curTs, prevTs float64
+	flush := func() {
+		ts := T.NowMS()
+		if em.stamp != nil {
+			ts = em.stamp(curTs)
 		}
+		em.emit(&t, AggrSystem, "", curTs-prevTs, ts)
+		t.reset()
+		prevTs = curTs
+	}
+	// 마지막 인터벌은 다음 타임스탬프가 없으니 종료 시 내보냄
+	defer func() {
+		if len(t.counts) > 0 {
+			flush()
+		}
+	}()
 
-		for sc.Scan() {
-			cols := strings.Split(sc.Text(), ",")
-			// perf -x, 포맷: time, value, unit, event, runtime, CPUs
-			// 최소 4컬럼 방어
-			if len(cols) < 4 {
-				continue
-			}
-			valStr := strings.TrimSpace(cols[1])
-			ev := strings.TrimSpace(cols[3])
-			if valStr == "" || strings.Contains(valStr, "not counted") || strings.Contains(ev, "duration_time") {
-				continue
+	for sc.Scan() {
+		cols := strings.Split(sc.Text(), ",")
+		// perf -x, 포맷: time, value, unit, event, runtime, CPUs
+		// 최소 4컬럼 방어
+		var ts float64
+		var err error
+		if len(cols) >= 4 {
+			ts, err = strconv.ParseFloat(strings.TrimSpace(cols[0]), 64)
+		}
+		if len(cols) < 4 || err != nil {
+			if line := strings.TrimSpace(sc.Text()); line != "" && diag != nil {
+				diag(line)
 			}
-			// value는 샘플링 간격 동안의 증분
-			if strings.Contains(ev, "LLC-loads") {
-				if v, e := strconv.ParseUint(valStr, 10, 64); e == nil {
-					loads += v; haveL = true
-				}
-			} else if strings.Contains(ev, "LLC-load-misses") {
-				if v, e := strconv.ParseUint(valStr, 10, 64); e == nil {
-					lmiss += v; haveLM = true
-				}
-			} else if strings.Contains(ev, "LLC-stores") {
-				if v, e := strconv.ParseUint(valStr, 10, 64); e == nil {
-					stores += v; haveS = true
-				}
-			} else if strings.Contains(ev, "LLC-store-misses") {
-				if v, e := strconv.ParseUint(valStr, 10, 64); e == nil {
-					smiss += v; haveSM = true
-				}
-			} else if ev == "instructions" {
-				if v, e := strconv.ParseUint(valStr, 10, 64); e == nil {
-					instr = v; haveI = true
-				}
-			} else if strings.Contains(ev, "cas_count_rd") || strings.Contains(ev, "cas_count_read") {
-				if v, e := strconv.ParseFloat(valStr, 64); e == nil {
-					rd = v; haveRD = true
-				}
-			} else if strings.Contains(ev, "cas_count_wr") || strings.Contains(ev, "cas_count_write") {
-				if v, e := strconv.ParseFloat(valStr, 64); e == nil {
-					wr = v; haveWR = true
-				}
+			continue
+		}
+		// 타임스탬프가 바뀌면 이전 인터벌 완료. 실제 간격으로 MB/s 계산
+		if ts != curTs {
+			if len(t.counts) > 0 {
+				flush()
+			} else {
+				prevTs = curTs
 			}
-
-			// 한 틱 완료 조건: 최소 instructions를 만난 시점으로 가정
-			if haveI {
-				// LLC 샘플
-				if haveL || haveLM || haveS || haveSM {
-					totAcc := loads + stores
-					totMiss := lmiss + smiss
-					var mpki, hit float64
-					if instr > 0 {
-						mpki = 1000.0 * float64(totMiss) / float64(instr)
-					}
-					if totAcc > 0 {
-						hit = 1.0 - float64(totMiss)/float64(totAcc)
-					}
-					llc := T.LLCSample{
-						MPKI: mpki, HitRate: hit,
-						Loads: loads, Stores: stores, Misses: totMiss,
-						Instr: instr, Ts: T.NowMS(), Source: "perf",
-					}
-					select { case llcCh <- llc: default: }
-				}
-
-				// MemBW 샘플
-				if haveRD && haveWR {
-					totalMBs := ((rd + wr) * 64.0) / (1024.0 * 1024.0) / sec
-					readMBs := (rd * 64.0) / (1024.0 * 1024.0) / sec
-					writeMBs := (wr * 64.0) / (1024.0 * 1024.0) / sec
-					mb := T.MemBw{
-						Source: "perf", ReadMBs: readMBs, WriteMBs: writeMBs,
-						TotalMBs: totalMBs, Ts: T.NowMS(),
-					}
-					select { case memCh <- mb: default: }
-				}
-				reset()
+			curTs = ts
+		}
+		valStr := strings.TrimSpace(cols[1])
+		ev := strings.TrimSpace(cols[3])
+		if valStr == "" || strings.Contains(ev, "duration_time") {
+			continue
+		}
+		// 이번 인터벌에 전혀 못 돈 카운터: 비율 0 으로 기록해야 신뢰도가 떨어져 표시/드랍됨
+		// (건너뛰면 0 으로 나눈 식이 신뢰도 1 의 0 값이 됨)
+		if strings.Contains(valStr, "not counted") {
+			t.add(ev, 0, 0)
+			continue
+		}
+		// value는 샘플링 간격 동안의 증분 (perf가 이미 enabled/running 으로 스케일한 값)
+		// cols[5]: 카운터가 실제로 돈 시간 비율(%)
+		ratio := 1.0
+		if len(cols) > 5 {
+			if p, e := strconv.ParseFloat(strings.TrimSpace(cols[5]), 64); e == nil {
+				ratio = p / 100.0
 			}
 		}
-	}()
-	return memCh, llcCh, nil
+		if v, e := strconv.ParseFloat(valStr, 64); e == nil {
+			t.add(ev, v, ratio)
+		}
+	}
 }
@@ -0,0 +1,133 @@
+package perf
+
+import (
+	"context"
+	"math"
+	"os"
+	"testing"
+	"time"
+)
+
+// 재생 결과를 받은 순서대로
+type replayed struct {
+	kind string // metric|llc|topdown|mem
+	ts   int64
+	name string // metric 이름
+	val  float64
+	low  bool
+}
+
+func replayCapture(t *testing.T, path string, start time.Time) []replayed {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	cfg := DefaultConfig(time.Second)
+	cfg.MinConfidence = 0.5
+	s, err := Replay(context.Background(), f, cfg, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []replayed
+	for s.Mem != nil || s.LLC != nil || s.Metrics != nil || s.TopDown != nil {
+		select {
+		case m, ok := <-s.Mem:
+			if !ok {
+				s.Mem = nil
+				continue
+			}
+			out = append(out, replayed{kind: "mem", ts: m.Ts, val: m.ReadMBs, low: m.LowConfidence})
+		case l, ok := <-s.LLC:
+			if !ok {
+				s.LLC = nil
+				continue
+			}
+			out = append(out, replayed{kind: "llc", ts: l.Ts, val: l.MPKI, low: l.LowConfidence})
+		case ms, ok := <-s.Metrics:
+			if !ok {
+				s.Metrics = nil
+				continue
+			}
+			out = append(out, replayed{kind: "metric", ts: ms.Ts, name: ms.Name, val: ms.Value, low: ms.LowConfidence})
+		case td, ok := <-s.TopDown:
+			if !ok {
+				s.TopDown = nil
+				continue
+			}
+			out = append(out, replayed{kind: "topdown", ts: td.Ts, low: td.LowConfidence})
+		}
+	}
+	return out
+}
+
+func TestReplayCapture(t *testing.T) {
+	start := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	got := replayCapture(t, "testdata/stat-interval.csv", start)
+
+	// cols[0] 이 바뀔 때마다 인터벌 하나: 1s, 2s, 4s (2s→4s 는 perf 가 인터벌 하나를 놓친 캡처)
+	base := start.UnixMilli()
+	wantTs := []int64{base + 1000, base + 2000, base + 4000}
+	var mem []replayed
+	for i, r := range got {
+		if i > 0 && r.ts < got[i-1].ts {
+			t.Fatalf("sample %d (%s) at %d after %d: not in interval order", i, r.kind, r.ts, got[i-1].ts)
+		}
+		if r.kind == "mem" {
+			mem = append(mem, r)
+		}
+	}
+	if len(mem) != len(wantTs) {
+		t.Fatalf("got %d MemBW samples, want %d", len(mem), len(wantTs))
+	}
+	for i, m := range mem {
+		if m.ts != wantTs[i] {
+			t.Errorf("interval %d: ts = %d, want %d", i, m.ts, wantTs[i])
+		}
+		// 세 인터벌 모두 10 MiB/s: 마지막은 두 배의 CAS 를 실제 간격 2s 로 나눈 값
+		if math.Abs(m.val-10) > 1e-9 {
+			t.Errorf("interval %d: read = %v MB/s, want 10", i, m.val)
+		}
+	}
+
+	// 인터벌 안에서는 메트릭, LLC, MemBW 순. 두 번째 인터벌의 LLC-loads 는 <not counted>
+	var order []string
+	for _, r := range got {
+		if r.ts != wantTs[1] {
+			continue
+		}
+		order = append(order, r.kind)
+		switch {
+		case r.kind == "llc" && !r.low:
+			t.Error("LLC sample with <not counted> LLC-loads: want low confidence")
+		case r.kind == "metric" && r.name == "llc_hit_rate" && !r.low:
+			t.Error("llc_hit_rate with <not counted> LLC-loads: want low confidence")
+		case r.kind == "mem" && r.low:
+			t.Error("MemBW sample does not use LLC-loads: want full confidence")
+		}
+	}
+	wantOrder := []string{"metric", "metric", "metric", "metric", "metric", "llc", "mem"}
+	if len(order) != len(wantOrder) {
+		t.Fatalf("second interval = %v, want %v", order, wantOrder)
+	}
+	for i := range order {
+		if order[i] != wantOrder[i] {
+			t.Fatalf("second interval = %v, want %v", order, wantOrder)
+		}
+	}
+}
+
+// -start 없이 재생하면 캡처 경과 시간이 그대로 Ts
+func TestReplayWithoutStart(t *testing.T) {
+	got := replayCapture(t, "testdata/stat-interval.csv", time.Time{})
+	var ts []int64
+	for _, r := range got {
+		if r.kind == "mem" {
+			ts = append(ts, r.ts)
+		}
+	}
+	if len(ts) != 3 || ts[0] != 1000 || ts[1] != 2000 || ts[2] != 4000 {
+		t.Fatalf("MemBW ts = %v, want [1000 2000 4000]", ts)
+	}
+}
@@ -0,0 +1,200 @@
+package perf
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	T "resmon/pkg/types"
+)
+
+// 수집기 상태 (Health.State)
+const (
+	StateRunning    = "running"
+	StateRestarting = "restarting"
+	StateFailed     = "failed" // Health.State 는 "failed: <reason>"
+)
+
+// perf stat 재시작 정책
+const (
+	restartMinBackoff = time.Second
+	restartMaxBackoff = time.Minute
+	// 이만큼 돌았으면 정상 실행으로 보고 backoff/연속 실패 횟수 초기화
+	healthyRunTime = 30 * time.Second
+	// 정상 실행 없이 연속으로 죽은 횟수가 이걸 넘으면 포기 (채널 닫힘)
+	maxRestartFailures = 8
+	// 진단용으로 보관할 perf 에러 출력 줄 수
+	stderrKeepLines = 20
+)
+
+// 수집기 상태 스냅샷
+type Health struct {
+	State    string   // running | restarting | failed: <reason>
+	Restarts int      // perf 재시작 횟수
+	LastExit string   // 마지막 perf 종료 원인
+	Stderr   []string // perf 가 남긴 최근 에러 출력 (CSV 아닌 줄)
+	Since    int64    // 현재 상태가 된 시각 (unix ms)
+}
+
+// 모니터 상태. 수집 고루틴이 갱신하고 아무 데서나 Health()로 조회
+type Status struct {
+	mu sync.Mutex
+	h  Health
+}
+
+func newStatus() *Status {
+	s := &Status{}
+	s.set(StateRunning)
+	return s
+}
+
+func (s *Status) Health() Health {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h := s.h
+	h.Stderr = append([]string(nil), s.h.Stderr...)
+	return h
+}
+
+func (s *Status) set(state string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.h.State = state
+	s.h.Since = T.NowMS()
+}
+
+func (s *Status) exited(reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.h.LastExit = reason
+}
+
+func (s *Status) restarted() {
+	s.mu.Lock()
+	s.h.Restarts++
+	s.mu.Unlock()
+	s.set(StateRunning)
+}
+
+// perf 출력 중 CSV가 아닌 줄 (Error:, 이벤트 거부 메시지 등) 보관
+func (s *Status) logStderr(line string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.h.Stderr = append(s.h.Stderr, line)
+	if n := len(s.h.Stderr); n > stderrKeepLines {
+		s.h.Stderr = append(s.h.Stderr[:0:0], s.h.Stderr[n-stderrKeepLines:]...)
+	}
+}
+
+// 실행 중인 perf stat 프로세스 하나
+type perfProc struct {
+	cmd *exec.Cmd
+	out *os.File // perf 는 stderr 에 출력
+}
+
+func startPerf(args []string) (*perfProc, error) {
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command("perf", args...)
+	cmd.Stderr = pw
+	// perf 와 워크로드(sleep)를 한 그룹으로 묶어 같이 정리
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		pr.Close()
+		pw.Close()
+		return nil, err
+	}
+	pw.Close()
+	return &perfProc{cmd: cmd, out: pr}, nil
+}
+
+func (p *perfProc) killGroup() {
+	_ = unix.Kill(-p.cmd.Process.Pid, unix.SIGKILL)
+}
+
+// perf 가 끝날 때까지 출력을 파싱하고 종료 원인 반환
+func (p *perfProc) run(ctx context.Context, em *emitter, st *Status) string {
+	waitErr := make(chan error, 1)
+	go func() {
+		err := p.cmd.Wait()
+		// perf 만 죽으면 sleep 이 파이프를 물고 있어 EOF가 안 옴
+		p.killGroup()
+		waitErr <- err
+	}()
+	stop := context.AfterFunc(ctx, p.killGroup)
+	defer stop()
+
+	var last string // 이번 실행의 마지막 에러 출력
+	parseStat(p.out, em, func(line string) {
+		last = line
+		st.logStderr(line)
+	})
+	p.out.Close()
+	return exitReason(<-waitErr, last)
+}
+
+// perf stat 을 띄우고 죽으면 지수 backoff 로 다시 띄움. 출력 채널은 재시작 사이에도 유지
+// 첫 실행 실패(perf 없음 등)는 바로 에러로 돌려줌
+func superviseStat(ctx context.Context, args []string, em *emitter) (*Status, error) {
+	proc, err := startPerf(args)
+	if err != nil {
+		return nil, err
+	}
+	st := newStatus()
+
+	go func() {
+		defer em.close()
+		backoff := restartMinBackoff
+		fails := 0
+		for {
+			started := time.Now()
+			reason := proc.run(ctx, em, st)
+			if ctx.Err() != nil {
+				return
+			}
+			if time.Since(started) >= healthyRunTime {
+				backoff, fails = restartMinBackoff, 0
+			}
+			for {
+				st.exited(reason)
+				if fails++; fails > maxRestartFailures {
+					st.set(StateFailed + ": " + reason)
+					return
+				}
+				st.set(StateRestarting)
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(backoff):
+				}
+				backoff = min(backoff*2, restartMaxBackoff)
+				var err error
+				if proc, err = startPerf(args); err == nil {
+					break
+				}
+				reason = err.Error()
+			}
+			st.restarted()
+		}
+	}()
+	return st, nil
+}
+
+func exitReason(err error, stderr string) string {
+	reason := "perf exited"
+	if err != nil {
+		reason = "perf " + err.Error()
+	}
+	if stderr != "" {
+		reason = fmt.Sprintf("%s (%s)", reason, stderr)
+	}
+	return reason
+}
@@ -0,0 +1,111 @@
+package perf
+
+import (
+	"strings"
+)
+
+// 한 인터벌 동안 누적된 카운터 값 (exec/native 백엔드 공용)
+// 설정 이벤트 이름과 분류 키(llc_loads, cas_rd, ...) 양쪽으로 누적
+type tick struct {
+	counts map[string]float64
+	// 멀티플렉싱 비율(time_running/time_enabled)의 키별 최솟값
+	ratio map[string]float64
+}
+
+// LLC 분류 키. 하나라도 있으면 LLC 샘플을 만들고 나머지는 0으로 채움
+var llcKeys = []string{"llc_loads", "llc_load_misses", "llc_stores", "llc_store_misses", "instructions"}
+
+// 이벤트 이름 → 분류 키 (perf 이벤트 이름/별칭 차이 흡수)
+func canonicalEvent(ev string) string {
+	switch {
+	case strings.Contains(ev, "LLC-loads"):
+		return "llc_loads"
+	case strings.Contains(ev, "LLC-load-misses"):
+		return "llc_load_misses"
+	case strings.Contains(ev, "LLC-stores"):
+		return "llc_stores"
+	case strings.Contains(ev, "LLC-store-misses"):
+		return "llc_store_misses"
+	case ev == "instructions":
+		return "instructions"
+	case strings.Contains(ev, "cas_count_rd") || strings.Contains(ev, "cas_count_read"):
+		return "cas_rd"
+	case strings.Contains(ev, "cas_count_wr") || strings.Contains(ev, "cas_count_write"):
+		return "cas_wr"
+	}
+	return ""
+}
+
+// 이벤트 값 누적 (uncore 박스/CPU별 값은 합산)
+// v는 이미 enabled/running 으로 스케일된 값, ratio는 running/enabled
+func (t *tick) add(ev string, v, ratio float64) {
+	t.put(ev, v, ratio)
+	if k := canonicalEvent(ev); k != "" && k != ev {
+		t.put(k, v, ratio)
+	}
+}
+
+func (t *tick) put(k string, v, ratio float64) {
+	if t.counts == nil {
+		t.counts = map[string]float64{}
+		t.ratio = map[string]float64{}
+	}
+	if r, ok := t.ratio[k]; !ok || ratio < r {
+		t.ratio[k] = ratio
+	}
+	t.counts[k] += v
+}
+
+func (t *tick) has(k string) bool {
+	_, ok := t.counts[k]
+	return ok
+}
+
+func (t *tick) hasLLC() bool {
+	for _, k := range llcKeys[:4] {
+		if t.has(k) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *tick) reset() {
+	clear(t.counts)
+	clear(t.ratio)
+}
+
+// 식 평가용 변수: 카운터 합계 + interval_s + cacheline_bytes
+func (t *tick) vars(sec float64) map[string]float64 {
+	vars := make(map[string]float64, len(t.counts)+len(llcKeys)+2)
+	for k, v := range t.counts {
+		vars[k] = v
+	}
+	if t.hasLLC() {
+		for _, k := range llcKeys {
+			if _, ok := vars[k]; !ok {
+				vars[k] = 0
+			}
+		}
+	}
+	vars["interval_s"] = sec
+	vars["cacheline_bytes"] = cacheLineBytes
+	return vars
+}
+
+// 변수들의 최소 멀티플렉싱 비율. 카운터가 아닌 변수는 derived(앞서 계산한 메트릭의 신뢰도)를 보고, 없으면 무시
+func (t *tick) confidence(names []string, derived map[string]float64) float64 {
+	c := 1.0
+	for _, n := range names {
+		r, ok := t.ratio[n]
+		if !ok {
+			if r, ok = derived[n]; !ok {
+				continue
+			}
+		}
+		if r < c {
+			c = r
+		}
+	}
+	return c
+}
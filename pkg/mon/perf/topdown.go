@@ -0,0 +1,90 @@
+package perf
+
+import (
+	"fmt"
+	"strings"
+
+	T "resmon/pkg/types"
+)
+
+// TMA level 1 이벤트 세트
+const (
+	// Ice Lake 이후: slots 리더 + PERF_METRICS 기반 topdown-* (값은 slot 수)
+	TopDownPerfMetrics = "perf-metrics"
+	// Skylake 계열: topdown-total-slots 등 카운터 조합으로 계산
+	TopDownLegacy = "legacy"
+
+	// 틱에 들어 있는 이벤트로 세트 결정 (재생용)
+	topdownAuto = "auto"
+)
+
+var topdownEvents = map[string][]string{
+	TopDownPerfMetrics: {"slots", "topdown-retiring", "topdown-bad-spec", "topdown-fe-bound", "topdown-be-bound"},
+	TopDownLegacy:      {"topdown-total-slots", "topdown-slots-issued", "topdown-slots-retired", "topdown-fetch-bubbles", "topdown-recovery-bubbles"},
+}
+
+// 이 CPU가 지원하는 topdown 이벤트 세트 탐지 (cpu PMU의 sysfs events/ 기준)
+// 둘 다 없으면 에러 → topdown 수집만 끄고 나머지는 계속
+func DetectTopDown(sysfsRoot string) (mode string, events []string, err error) {
+	res := NewResolver(sysfsRoot)
+	for _, m := range []string{TopDownPerfMetrics, TopDownLegacy} {
+		evs, missing := res.Resolve(topdownEvents[m])
+		if len(missing) == 0 && len(evs) == len(topdownEvents[m]) {
+			return m, topdownEvents[m], nil
+		}
+	}
+	return "", nil, fmt.Errorf("topdown events not supported on this CPU")
+}
+
+// perf stat -e 용 그룹 표기. perf-metrics 는 slots 리더 그룹이어야 커널이 받아줌
+func topdownGroupArg(events []string) string {
+	return "{" + strings.Join(events, ",") + "}"
+}
+
+// 틱 누적값으로 frontend/backend/bad-spec/retiring 비율 계산
+func (t *tick) topdown(mode string) (T.TopDownSample, bool) {
+	if mode == topdownAuto {
+		for _, m := range []string{TopDownPerfMetrics, TopDownLegacy} {
+			if td, ok := t.topdown(m); ok {
+				return td, true
+			}
+		}
+		return T.TopDownSample{}, false
+	}
+	c := t.counts
+	var td T.TopDownSample
+	switch mode {
+	case TopDownPerfMetrics:
+		slots := c["slots"]
+		if slots == 0 || !t.hasAll(topdownEvents[mode]) {
+			return td, false
+		}
+		td.Retiring = c["topdown-retiring"] / slots
+		td.BadSpeculation = c["topdown-bad-spec"] / slots
+		td.FrontendBound = c["topdown-fe-bound"] / slots
+		td.BackendBound = c["topdown-be-bound"] / slots
+	case TopDownLegacy:
+		total := c["topdown-total-slots"]
+		if total == 0 || !t.hasAll(topdownEvents[mode]) {
+			return td, false
+		}
+		td.FrontendBound = c["topdown-fetch-bubbles"] / total
+		td.BadSpeculation = (c["topdown-slots-issued"] - c["topdown-slots-retired"] + c["topdown-recovery-bubbles"]) / total
+		td.Retiring = c["topdown-slots-retired"] / total
+		td.BackendBound = 1 - (td.FrontendBound + td.BadSpeculation + td.Retiring)
+	default:
+		return td, false
+	}
+	td.Mode = mode
+	td.Confidence = t.confidence(topdownEvents[mode], nil)
+	return td, true
+}
+
+func (t *tick) hasAll(keys []string) bool {
+	for _, k := range keys {
+		if !t.has(k) {
+			return false
+		}
+	}
+	return true
+}
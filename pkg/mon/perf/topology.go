@@ -0,0 +1,65 @@
+package perf
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// 집계 단위
+const (
+	AggrSystem = "system" // 전체 합산만
+	AggrCPU    = "cpu"
+	AggrSocket = "socket"
+	AggrNode   = "node" // NUMA 노드
+)
+
+// CPU 번호 → 소켓/NUMA 노드 (/sys/devices/system/cpu/cpuN 기준)
+type topology struct {
+	socket map[int]int
+	node   map[int]int
+}
+
+func loadTopology(sysRoot string, cpus []int) (*topology, error) {
+	tp := &topology{socket: map[int]int{}, node: map[int]int{}}
+	for _, cpu := range cpus {
+		dir := filepath.Join(sysRoot, "devices/system/cpu", "cpu"+strconv.Itoa(cpu))
+		s, err := readTrim(filepath.Join(dir, "topology/physical_package_id"))
+		if err != nil {
+			return nil, err
+		}
+		if tp.socket[cpu], err = strconv.Atoi(s); err != nil {
+			return nil, fmt.Errorf("cpu%d: bad physical_package_id %q", cpu, s)
+		}
+		// NUMA 노드는 cpuN/nodeX 심볼릭 링크로 표시됨 (NUMA 미지원 커널이면 0)
+		ents, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range ents {
+			if rest, ok := strings.CutPrefix(e.Name(), "node"); ok {
+				if n, err := strconv.Atoi(rest); err == nil {
+					tp.node[cpu] = n
+					break
+				}
+			}
+		}
+	}
+	return tp, nil
+}
+
+// 집계 모드에 맞는 라벨 ("cpu3", "socket0", "node1")
+// uncore 카운터는 CPU 단위 의미가 없어 cpu 모드에서도 소켓으로 묶음
+func (tp *topology) label(mode string, cpu int, uncore bool) string {
+	switch {
+	case mode == AggrCPU && !uncore:
+		return "cpu" + strconv.Itoa(cpu)
+	case mode == AggrCPU, mode == AggrSocket:
+		return "socket" + strconv.Itoa(tp.socket[cpu])
+	case mode == AggrNode:
+		return "node" + strconv.Itoa(tp.node[cpu])
+	}
+	return AggrSystem
+}
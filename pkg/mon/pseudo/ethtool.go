@@ -0,0 +1,242 @@
+package pseudo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+
+	T "resmon/pkg/types"
+)
+
+// include/uapi/linux/ethtool.h
+const (
+	ethSSStats     = 1 // ETH_SS_STATS
+	ethGStringLen  = 32
+	ethtoolMaxStat = 1 << 16 // 드라이버가 말도 안 되는 n_stats 를 줄 때 방어
+)
+
+// 드라이버 통계 이름 중 큐별 패킷/바이트 카운터
+// rx_queue_0_packets (ixgbe, 예전 virtio_net), rx0_bytes (mlx5), rx-0.packets (i40e), tx_queue_3_bytes ...
+var queueStatRe = regexp.MustCompile(`^(rx|tx)[_-]?(?:queue[_-]?)?(\d+)[_.](packets|bytes)$`)
+
+// SIOCETHTOOL 용 struct ifreq (ifr_name + ifr_data 포인터)
+type ethtoolIfreq struct {
+	name [unix.IFNAMSIZ]byte
+	data unsafe.Pointer
+	_    [24 - unsafe.Sizeof(uintptr(0))]byte
+}
+
+func ethtoolIoctl(fd int, iface string, data unsafe.Pointer) error {
+	var ifr ethtoolIfreq
+	if len(iface) >= unix.IFNAMSIZ {
+		return fmt.Errorf("interface name too long: %s", iface)
+	}
+	copy(ifr.name[:], iface)
+	ifr.data = data
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), unix.SIOCETHTOOL, uintptr(unsafe.Pointer(&ifr)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+type queueKey struct {
+	dir   string // rx|tx
+	queue int
+	kind  string // packets|bytes
+}
+
+// 큐 카운터 하나의 위치 (GSTATS 결과 배열 인덱스)
+type queueStat struct {
+	queueKey
+	idx int
+}
+
+// 인터페이스별 ethtool 통계 리더
+// 이름 목록(GSTRINGS)은 통계 개수가 바뀔 때만 다시 받고, 지원 안 하는 장치(lo, dummy 등)는 기억해 두고 건너뜀
+type ethtoolReader struct {
+	fd          int
+	layout      map[string][]queueStat // iface → 큐 카운터 위치
+	nstats      map[string]int
+	unsupported map[string]bool
+}
+
+func newEthtoolReader() (*ethtoolReader, error) {
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("ethtool socket: %w", err)
+	}
+	return &ethtoolReader{
+		fd:          fd,
+		layout:      map[string][]queueStat{},
+		nstats:      map[string]int{},
+		unsupported: map[string]bool{},
+	}, nil
+}
+
+func (r *ethtoolReader) close() { unix.Close(r.fd) }
+
+// 사라진 인터페이스의 캐시 정리 (같은 이름으로 다른 장치가 생길 수 있음)
+func (r *ethtoolReader) forget(iface string) {
+	delete(r.layout, iface)
+	delete(r.nstats, iface)
+	delete(r.unsupported, iface)
+}
+
+// 큐별 카운터. 지원 안 하면 nil
+// 커널은 GSTRINGS/GSTATS 에서 호출자가 준 개수를 무시하고 그 순간의 개수만큼 복사하므로
+// (ethtool -L 로 큐 수를 바꾸면 늘어남) 매번 GDRVINFO 로 개수를 먼저 보고, 바뀌었으면 이름부터 다시 받음
+func (r *ethtoolReader) read(iface string) map[queueKey]uint64 {
+	if r.unsupported[iface] {
+		return nil
+	}
+	n, err := r.count(iface)
+	if err != nil {
+		r.unsupported[iface] = true
+		return nil
+	}
+	layout, ok := r.layout[iface]
+	if !ok || r.nstats[iface] != n {
+		if layout, err = r.loadLayout(iface, n); err != nil {
+			// 바뀌는 중일 수 있음 → 다음 틱에 다시
+			r.forget(iface)
+			return nil
+		}
+		if len(layout) == 0 {
+			r.unsupported[iface] = true
+			return nil
+		}
+	}
+	vals, err := r.stats(iface, n)
+	if err != nil {
+		// 그 사이 개수가 또 바뀜 → 다음 틱에 이름부터 다시
+		r.forget(iface)
+		return nil
+	}
+	out := make(map[queueKey]uint64, len(layout))
+	for _, q := range layout {
+		if q.idx < len(vals) {
+			out[q.queueKey] = vals[q.idx]
+		}
+	}
+	return out
+}
+
+// 지금 드라이버 통계 개수 (ETH_SS_STATS 의 get_sset_count)
+func (r *ethtoolReader) count(iface string) (int, error) {
+	info, err := unix.IoctlGetEthtoolDrvinfo(r.fd, iface)
+	if err != nil {
+		return 0, err
+	}
+	n := int(info.N_stats)
+	if n == 0 || n > ethtoolMaxStat {
+		return 0, fmt.Errorf("%s: no driver statistics", iface)
+	}
+	return n, nil
+}
+
+func (r *ethtoolReader) loadLayout(iface string, n int) ([]queueStat, error) {
+	names, err := r.strings(iface, n)
+	if err != nil {
+		return nil, err
+	}
+	var layout []queueStat
+	for i, name := range names {
+		m := queueStatRe.FindStringSubmatch(name)
+		if m == nil {
+			continue
+		}
+		q, _ := strconv.Atoi(m[2])
+		layout = append(layout, queueStat{queueKey{dir: m[1], queue: q, kind: m[3]}, i})
+	}
+	r.layout[iface] = layout
+	r.nstats[iface] = n
+	return layout, nil
+}
+
+// 개수 확인과 ioctl 사이에 늘어나도 버퍼 밖에 쓰지 않도록 여유를 둠
+func ethtoolRoom(n int) int { return n + n/4 + 16 }
+
+// ETHTOOL_GSTRINGS: struct ethtool_gstrings { cmd, string_set, len u32; data[len*32] }
+// 커널이 채운 len 이 n 과 다르면 (그 사이 바뀜) 오류
+func (r *ethtoolReader) strings(iface string, n int) ([]string, error) {
+	buf := make([]byte, 12+ethtoolRoom(n)*ethGStringLen)
+	binary.NativeEndian.PutUint32(buf[0:], unix.ETHTOOL_GSTRINGS)
+	binary.NativeEndian.PutUint32(buf[4:], ethSSStats)
+	binary.NativeEndian.PutUint32(buf[8:], uint32(n))
+	if err := ethtoolIoctl(r.fd, iface, unsafe.Pointer(&buf[0])); err != nil {
+		return nil, err
+	}
+	if got := int(binary.NativeEndian.Uint32(buf[8:])); got != n {
+		return nil, fmt.Errorf("%s: driver statistics count changed (%d → %d)", iface, n, got)
+	}
+	names := make([]string, n)
+	for i := range names {
+		s := buf[12+i*ethGStringLen : 12+(i+1)*ethGStringLen]
+		if j := bytes.IndexByte(s, 0); j >= 0 {
+			s = s[:j]
+		}
+		names[i] = string(s)
+	}
+	return names, nil
+}
+
+// ETHTOOL_GSTATS: struct ethtool_stats { cmd, n_stats u32; data[n_stats] u64 }
+func (r *ethtoolReader) stats(iface string, n int) ([]uint64, error) {
+	buf := make([]uint64, 1+ethtoolRoom(n)) // 앞 8바이트가 cmd, n_stats
+	hdr := (*[2]uint32)(unsafe.Pointer(&buf[0]))
+	hdr[0], hdr[1] = unix.ETHTOOL_GSTATS, uint32(n)
+	if err := ethtoolIoctl(r.fd, iface, unsafe.Pointer(&buf[0])); err != nil {
+		return nil, err
+	}
+	if int(hdr[1]) != n {
+		return nil, fmt.Errorf("%s: driver statistics count changed (%d → %d)", iface, n, hdr[1])
+	}
+	return buf[1 : 1+n], nil
+}
+
+// 큐 카운터 초당 비율. 줄어든 카운터(큐 재설정)는 그 큐만 뺌
+func queueRates(cur, prev map[queueKey]uint64, dt float64) []T.NetQueue {
+	if len(cur) == 0 || dt <= 0 {
+		return nil
+	}
+	type qid struct {
+		dir   string
+		queue int
+	}
+	byQueue := map[qid]*T.NetQueue{}
+	for k, v := range cur {
+		p, ok := prev[k]
+		if !ok || v < p {
+			continue
+		}
+		nq := byQueue[qid{k.dir, k.queue}]
+		if nq == nil {
+			nq = &T.NetQueue{Dir: k.dir, Queue: k.queue}
+			byQueue[qid{k.dir, k.queue}] = nq
+		}
+		rate := float64(v-p) / dt
+		if k.kind == "packets" {
+			nq.Pps = rate
+		} else {
+			nq.Bps = rate
+		}
+	}
+	out := make([]T.NetQueue, 0, len(byQueue))
+	for _, nq := range byQueue {
+		out = append(out, *nq)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Dir != out[j].Dir {
+			return out[i].Dir < out[j].Dir
+		}
+		return out[i].Queue < out[j].Queue
+	})
+	return out
+}
@@ -3,17 +3,24 @@ package pseudo
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io/fs"
+	"math"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
-	"time"
 	"strings"
-	"fmt"
+	"time"
 
 	T "resmon/pkg/types"
 )
 
+const sysClassNet = "/sys/class/net"
+
+// 인터페이스가 많아도 한 틱 분량이 들어가도록 넉넉히
+const netChanSize = 256
+
 func readUintFrom(path string) (uint64, error) {
 	b, err := os.ReadFile(path)
 	if err != nil {
@@ -32,40 +39,376 @@ func readUintFrom(path string) (uint64, error) {
 // 작은 의존 제거용
 func fmtSscanf(s, f string, a ...any) (int, error) { return fmt.Sscanf(s, f, a...) }
 
-func SpawnNetWatcher(ctx context.Context, iface string, interval time.Duration) (<-chan T.NetSample, error) {
-	base := "/sys/class/net/" + iface + "/statistics"
-	_, err := os.Stat(base)
-	if errors.Is(err, fs.ErrNotExist) {
+// 감시할 인터페이스 선택: 이름 또는 glob(eth*), "!veth*" 처럼 !로 시작하면 제외
+// 포함 패턴이 하나도 없으면 제외 패턴에 안 걸리는 모든 인터페이스
+type NetSelector struct {
+	include []string
+	exclude []string
+}
+
+func NewNetSelector(patterns []string) (*NetSelector, error) {
+	s := &NetSelector{}
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		neg := strings.HasPrefix(p, "!")
+		p = strings.TrimPrefix(p, "!")
+		if _, err := filepath.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("bad interface pattern %q: %w", p, err)
+		}
+		if neg {
+			s.exclude = append(s.exclude, p)
+		} else {
+			s.include = append(s.include, p)
+		}
+	}
+	return s, nil
+}
+
+func (s *NetSelector) Match(iface string) bool {
+	for _, p := range s.exclude {
+		if ok, _ := filepath.Match(p, iface); ok {
+			return false
+		}
+	}
+	if len(s.include) == 0 {
+		return true
+	}
+	for _, p := range s.include {
+		if ok, _ := filepath.Match(p, iface); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// 지금 있는 인터페이스 중 선택된 것 (이름순)
+func (s *NetSelector) list() ([]string, error) {
+	ents, err := os.ReadDir(sysClassNet)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, e := range ents {
+		// 인터페이스는 장치 디렉터리로 가는 심볼릭 링크. bonding 모듈의 bonding_masters 같은 일반 파일은 뺌
+		if e.Type().IsRegular() {
+			continue
+		}
+		if s.Match(e.Name()) {
+			out = append(out, e.Name())
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// 네트워크 감시 설정
+type NetConfig struct {
+	Interfaces []string // 이름 또는 glob, "!"로 시작하면 제외
+	Interval   time.Duration
+	// 호스트 인터페이스 카운터 읽는 방법: "sysfs"(기본, 인터페이스마다 statistics/ 파일) | "netlink"(RTM_GETLINK 덤프 한 번)
+	Backend string
+	// statistics/ 아래에서 초당 비율로 같이 낼 카운터 (rx_packets, rx_dropped, rx_missed_errors, ...)
+	Counters []string
+	// speed 가 없는 장치(bond, veth, 터널 등)의 용량(Mb/s). 키는 인터페이스 이름 또는 glob
+	NominalMbps map[string]int64
+	// ethtool 드라이버 통계(SIOCETHTOOL GSTRINGS/GSTATS)로 큐별 패킷/바이트 비율도 냄 (호스트 네임스페이스만)
+	// 드라이버가 지원 안 하거나 큐 통계가 없으면(lo, dummy 등) Queues 없이 보냄
+	PerQueue bool
+
+	// 다른 네트워크 네임스페이스의 인터페이스 감시 (둘 중 하나만)
+	// Netns: /var/run/netns/<name> 의 이름 또는 네임스페이스 파일 경로 (setns, CAP_SYS_ADMIN 필요)
+	// NetnsPID: 이 프로세스의 네임스페이스 (/proc/<pid>/net/dev)
+	// 다른 네임스페이스의 sysfs 는 안 보이므로 speed/duplex/operstate 는 없음 (NominalMbps 로 용량 지정)
+	Netns    string
+	NetnsPID int
+}
+
+// 카운터를 읽어 오는 곳 (호스트 sysfs, 다른 네임스페이스의 /proc/.../net/dev)
+type netSource interface {
+	// 선택된 인터페이스들의 현재 카운터. 인터페이스별 실패는 netRead.err
+	scan(sel *NetSelector, counters []string) ([]netRead, error)
+	link(iface string) linkInfo
+}
+
+type netRead struct {
+	iface string
+	st    netState
+	err   error
+}
+
+// 호스트 네임스페이스: /sys/class/net/<if>/statistics
+type sysfsNetSource struct{}
+
+func (sysfsNetSource) scan(sel *NetSelector, counters []string) ([]netRead, error) {
+	ifaces, err := sel.list()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]netRead, 0, len(ifaces))
+	for _, iface := range ifaces {
+		st, err := readNetState(iface, counters)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue // 목록을 읽은 사이에 사라짐
+		}
+		out = append(out, netRead{iface, st, err})
+	}
+	return out, nil
+}
+
+func (sysfsNetSource) link(iface string) linkInfo { return readLinkInfo(iface) }
+
+// 링크 속도/duplex/operstate. 링크가 내려가 있거나 가상 장치면 speed 는 EINVAL 이나 -1
+type linkInfo struct {
+	speedMbps int64
+	duplex    string
+	operState string
+}
+
+func readLinkInfo(iface string) linkInfo {
+	li := readLinkSpeed(iface)
+	if b, err := os.ReadFile(filepath.Join(sysClassNet, iface, "operstate")); err == nil {
+		li.operState = strings.TrimSpace(string(b))
+	}
+	return li
+}
+
+// sysfs 의 speed, duplex (operstate 는 비워 둠)
+func readLinkSpeed(iface string) linkInfo {
+	dir := filepath.Join(sysClassNet, iface)
+	var li linkInfo
+	if b, err := os.ReadFile(filepath.Join(dir, "speed")); err == nil {
+		if v, err := strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64); err == nil && v > 0 {
+			li.speedMbps = v
+		}
+	}
+	if b, err := os.ReadFile(filepath.Join(dir, "duplex")); err == nil {
+		li.duplex = strings.TrimSpace(string(b))
+	}
+	return li
+}
+
+// 설정된 nominal 용량 찾기. 정확한 이름이 glob 보다 우선, glob 끼리는 이름순 첫 매치
+func nominalMbps(m map[string]int64, iface string) int64 {
+	if v, ok := m[iface]; ok {
+		return v
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if ok, _ := filepath.Match(k, iface); ok {
+			return m[k]
+		}
+	}
+	return 0
+}
+
+// rx/tx 가 용량에서 차지하는 비율. half duplex 는 두 방향이 한 회선을 나눠 씀
+func utilization(rbps, tbps uint64, mbps int64, duplex string) (rx, tx float64) {
+	if mbps <= 0 {
+		return 0, 0
+	}
+	capBps := float64(mbps) * 1e6 / 8
+	if duplex == "half" {
+		u := float64(rbps+tbps) / capBps
+		return u, u
+	}
+	return float64(rbps) / capBps, float64(tbps) / capBps
+}
+
+// 인터페이스별 직전 카운터
+type netState struct {
+	rx, tx   uint64
+	counters map[string]uint64
+	queues   map[queueKey]uint64
+	t        time.Time
+}
+
+// 없는 추가 카운터(드라이버마다 다름)는 건너뜀
+func readNetState(iface string, counters []string) (netState, error) {
+	base := filepath.Join(sysClassNet, iface, "statistics")
+	rx, err := readUintFrom(filepath.Join(base, "rx_bytes"))
+	if err != nil {
+		return netState{}, err
+	}
+	tx, err := readUintFrom(filepath.Join(base, "tx_bytes"))
+	if err != nil {
+		return netState{}, err
+	}
+	st := netState{rx: rx, tx: tx, t: time.Now()}
+	for _, c := range counters {
+		v, err := readUintFrom(filepath.Join(base, c))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return netState{}, err
+		}
+		if st.counters == nil {
+			st.counters = make(map[string]uint64, len(counters))
+		}
+		st.counters[c] = v
+	}
+	return st, nil
+}
+
+// 직전 값 대비 증가량
+// 줄었으면 32비트 카운터 wrap 인지 봄: 이전 값이 32비트 범위이고, wrap 으로 본 증가량이 maxDelta
+// (링크 속도 × 시간) 이하일 때만. 요즘 sysfs 카운터는 64비트라 대부분의 감소는 드라이버 리셋이므로
+// 속도를 모르면(maxDelta 0) wrap 으로 보지 않고 ok=false
+func counterDelta(cur, prev, maxDelta uint64) (delta uint64, ok bool) {
+	if cur >= prev {
+		return cur - prev, true
+	}
+	if prev <= math.MaxUint32 && maxDelta > 0 {
+		if d := cur + (1 << 32) - prev; d <= maxDelta {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// 링크 속도로 dt 동안 가능한 최대 바이트 수 (타이머 오차 10% 여유). 속도를 모르면 0
+// 패킷/에러 카운터도 바이트보다 클 수 없으니 같은 상한을 씀
+func wrapBound(speedMbps int64, dt float64) uint64 {
+	if speedMbps <= 0 || dt <= 0 {
+		return 0
+	}
+	return uint64(float64(speedMbps) * 1e6 / 8 * dt * 1.1)
+}
+
+// 직전 상태 대비 샘플. 리셋을 건넌 구간이면 Invalid (0 대신)
+// speedMbps: 32비트 wrap 판정용 링크 속도 (모르면 0)
+func (cur netState) sample(iface string, prev netState, speedMbps int64) T.NetSample {
+	ns := T.NetSample{Iface: iface, Ts: T.NowMS()}
+	dt := cur.t.Sub(prev.t).Seconds()
+	bound := wrapBound(speedMbps, dt)
+	rx, okR := counterDelta(cur.rx, prev.rx, bound)
+	tx, okT := counterDelta(cur.tx, prev.tx, bound)
+	if !okR || !okT || dt <= 0 {
+		ns.Invalid = true
+		return ns
+	}
+	ns.RxBps = uint64(float64(rx) / dt)
+	ns.TxBps = uint64(float64(tx) / dt)
+	for c, v := range cur.counters {
+		p, ok := prev.counters[c]
+		if !ok {
+			continue
+		}
+		d, ok := counterDelta(v, p, bound)
+		if !ok {
+			ns.Invalid = true
+			ns.RxBps, ns.TxBps, ns.Rates = 0, 0, nil
+			return ns
+		}
+		if ns.Rates == nil {
+			ns.Rates = make(map[string]float64, len(cur.counters))
+		}
+		ns.Rates[c] = float64(d) / dt
+	}
+	ns.Queues = queueRates(cur.queues, prev.queues, dt)
+	return ns
+}
+
+// 패턴에 맞는 인터페이스들을 감시해 인터페이스마다 NetSample 하나씩 같은 채널로 내보냄
+// 매 틱 인터페이스 목록을 다시 보고 새로 생긴 인터페이스는 추가(첫 틱은 기준값만), 사라진 건 뺌
+func SpawnNetWatcher(ctx context.Context, cfg NetConfig) (<-chan T.NetSample, error) {
+	sel, err := NewNetSelector(cfg.Interfaces)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range cfg.Counters {
+		if c == "" || strings.ContainsRune(c, '/') {
+			return nil, fmt.Errorf("bad network counter name %q", c)
+		}
+	}
+	src, label, err := newNetSource(cfg)
+	if err != nil {
 		return nil, err
 	}
-	out := make(chan T.NetSample, 8)
+	if _, err := src.scan(sel, cfg.Counters); err != nil {
+		return nil, err
+	}
+	var eth *ethtoolReader
+	if cfg.PerQueue {
+		if label != "" {
+			return nil, fmt.Errorf("per-queue statistics are available for the host namespace only")
+		}
+		if eth, err = newEthtoolReader(); err != nil {
+			return nil, err
+		}
+	}
+	out := make(chan T.NetSample, netChanSize)
 	go func() {
 		defer close(out)
-		rxPrev, _ := readUintFrom(filepath.Join(base, "rx_bytes"))
-		txPrev, _ := readUintFrom(filepath.Join(base, "tx_bytes"))
-		prevT := time.Now()
-		t := time.NewTicker(interval)
+		if eth != nil {
+			defer eth.close()
+		}
+		prev := map[string]netState{}
+		send := func(ns T.NetSample) {
+			ns.Netns = label
+			select {
+			case out <- ns:
+			default:
+			}
+		}
+		scan := func() {
+			reads, err := src.scan(sel, cfg.Counters)
+			if err != nil {
+				send(T.NetSample{Err: err.Error(), Invalid: true, Ts: T.NowMS()})
+				return
+			}
+			seen := make(map[string]bool, len(reads))
+			for _, r := range reads {
+				seen[r.iface] = true
+				if r.err != nil {
+					// 직전 값은 유지해서 다음 성공한 읽기가 그 구간 전체 비율을 냄
+					send(T.NetSample{Iface: r.iface, Err: r.err.Error(), Invalid: true, Ts: T.NowMS()})
+					continue
+				}
+				if eth != nil {
+					r.st.queues = eth.read(r.iface)
+				}
+				p, ok := prev[r.iface]
+				prev[r.iface] = r.st
+				if !ok {
+					continue
+				}
+				li := src.link(r.iface)
+				if li.speedMbps == 0 {
+					li.speedMbps = nominalMbps(cfg.NominalMbps, r.iface)
+				}
+				ns := r.st.sample(r.iface, p, li.speedMbps)
+				ns.SpeedMbps, ns.Duplex, ns.OperState = li.speedMbps, li.duplex, li.operState
+				if !ns.Invalid {
+					ns.RxUtil, ns.TxUtil = utilization(ns.RxBps, ns.TxBps, li.speedMbps, li.duplex)
+				}
+				send(ns)
+			}
+			for iface := range prev {
+				if !seen[iface] {
+					delete(prev, iface)
+					if eth != nil {
+						eth.forget(iface)
+					}
+				}
+			}
+		}
+		scan()
+		t := time.NewTicker(cfg.Interval)
 		defer t.Stop()
 		for {
 			select {
 			case <-ctx.Done():
 				return
 			case <-t.C:
-				rx, _ := readUintFrom(filepath.Join(base, "rx_bytes"))
-				tx, _ := readUintFrom(filepath.Join(base, "tx_bytes"))
-				now := time.Now()
-				dt := now.Sub(prevT).Seconds()
-				var rbps, tbps uint64
-				if rx >= rxPrev && tx >= txPrev && dt > 0 {
-					rbps = uint64(float64(rx-rxPrev) / dt)
-					tbps = uint64(float64(tx-txPrev) / dt)
-				}
-				prevT, rxPrev, txPrev = now, rx, tx
-				ns := T.NetSample{Iface: iface, RxBps: rbps, TxBps: tbps, Ts: T.NowMS()}
-				select {
-				case out <- ns:
-				default:
-				}
+				scan()
 			}
 		}
 	}()
@@ -0,0 +1,80 @@
+package pseudo
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCounterDelta(t *testing.T) {
+	const gbit = 1000 // Mb/s
+	tests := []struct {
+		name      string
+		cur, prev uint64
+		speedMbps int64
+		dt        float64
+		want      uint64
+		ok        bool
+	}{
+		{"increase", 3000, 1000, 0, 1, 2000, true},
+		{"unchanged", 1000, 1000, 0, 1, 0, true},
+		{"64-bit increase above 32 bits", math.MaxUint32 + 5000, math.MaxUint32 + 1000, 0, 1, 4000, true},
+		// 1Gb/s 로 1초에 2000바이트는 가능 → wrap
+		{"32-bit wrap within link bound", 1000, math.MaxUint32 - 999, gbit, 1, 2000, true},
+		// 10Mb/s 로 1초에 ~4GB 는 불가능 → 리셋
+		{"32-bit wrap beyond link bound", 50, 100, 10, 1, 0, false},
+		{"32-bit wrap with unknown speed", 1000, math.MaxUint32 - 999, 0, 1, 0, false},
+		{"drop from above 32 bits", 1000, math.MaxUint32 + 1, gbit, 1, 0, false},
+		{"wrap with dt 0", 1000, math.MaxUint32 - 999, gbit, 0, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := counterDelta(tt.cur, tt.prev, wrapBound(tt.speedMbps, tt.dt))
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("%s: counterDelta(%d, %d) = %d, %v; want %d, %v", tt.name, tt.cur, tt.prev, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestWrapBound(t *testing.T) {
+	tests := []struct {
+		speedMbps int64
+		dt        float64
+		want      uint64
+	}{
+		{1000, 1, 137500000}, // 125MB/s + 10%
+		{1000, 0.5, 68750000},
+		{0, 1, 0},
+		{-1, 1, 0}, // 링크 다운 시 sysfs speed
+		{1000, 0, 0},
+		{1000, -1, 0},
+	}
+	for _, tt := range tests {
+		if got := wrapBound(tt.speedMbps, tt.dt); got != tt.want {
+			t.Errorf("wrapBound(%d, %v) = %d, want %d", tt.speedMbps, tt.dt, got, tt.want)
+		}
+	}
+}
+
+func TestSampleInvalid(t *testing.T) {
+	now := time.Now()
+	prev := netState{t: now, rx: math.MaxUint32 - 999, tx: 1000}
+	tests := []struct {
+		name      string
+		cur       netState
+		speedMbps int64
+		invalid   bool
+	}{
+		{"wrap within bound", netState{t: now.Add(time.Second), rx: 1000, tx: 2000}, 1000, false},
+		{"wrap with unknown speed", netState{t: now.Add(time.Second), rx: 1000, tx: 2000}, 0, true},
+		{"dt 0", netState{t: now, rx: math.MaxUint32, tx: 2000}, 1000, true},
+	}
+	for _, tt := range tests {
+		ns := tt.cur.sample("eth0", prev, tt.speedMbps)
+		if ns.Invalid != tt.invalid {
+			t.Errorf("%s: Invalid = %v, want %v", tt.name, ns.Invalid, tt.invalid)
+		}
+		if !ns.Invalid && (ns.RxBps != 2000 || ns.TxBps != 1000) {
+			t.Errorf("%s: rx/tx = %d/%d B/s, want 2000/1000", tt.name, ns.RxBps, ns.TxBps)
+		}
+	}
+}
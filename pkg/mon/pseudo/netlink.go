@@ -0,0 +1,192 @@
+package pseudo
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// struct rtnl_link_stats64 필드 순서 → statistics/ 파일 이름
+// 커널 버전에 따라 뒤쪽 필드가 늘어나므로 받은 길이만큼만 읽음
+var linkStats64Fields = []string{
+	"rx_packets", "tx_packets", "rx_bytes", "tx_bytes",
+	"rx_errors", "tx_errors", "rx_dropped", "tx_dropped",
+	"multicast", "collisions",
+	"rx_length_errors", "rx_over_errors", "rx_crc_errors", "rx_frame_errors", "rx_fifo_errors", "rx_missed_errors",
+	"tx_aborted_errors", "tx_carrier_errors", "tx_fifo_errors", "tx_heartbeat_errors", "tx_window_errors",
+	"rx_compressed", "tx_compressed", "rx_nohandler", "rx_otherhost_dropped",
+}
+
+// IFLA_OPERSTATE 값(RFC 2863) → sysfs operstate 문자열
+var linkOperStates = []string{"unknown", "notpresent", "down", "lowerlayerdown", "testing", "dormant", "up"}
+
+// 덤프 한 건: rtnl_link_stats64 값 + operstate
+type nlLink struct {
+	stats     map[string]uint64
+	operState string
+}
+
+// 호스트 네임스페이스: RTM_GETLINK 덤프 한 번으로 모든 인터페이스의 IFLA_STATS64, IFLA_OPERSTATE
+// 인터페이스마다 sysfs 파일을 여러 개 여는 대신 틱당 netlink 요청 하나
+// speed/duplex 는 덤프에 없어 sysfs 에서 읽되, operstate 가 바뀔 때만 다시 읽음
+type netlinkNetSource struct {
+	seq   uint32
+	oper  map[string]string   // 마지막 덤프의 operstate
+	links map[string]linkInfo // speed/duplex 캐시 (읽을 때의 operstate 와 함께)
+}
+
+func (s *netlinkNetSource) scan(sel *NetSelector, counters []string) ([]netRead, error) {
+	links, err := s.dumpLinks()
+	if err != nil {
+		return nil, err
+	}
+	stats := make(map[string]map[string]uint64, len(links))
+	s.oper = make(map[string]string, len(links))
+	for iface, l := range links {
+		stats[iface] = l.stats
+		s.oper[iface] = l.operState
+	}
+	for iface := range s.links {
+		if _, ok := links[iface]; !ok {
+			delete(s.links, iface)
+		}
+	}
+	return statReads(stats, sel, counters, time.Now()), nil
+}
+
+// 링크가 다시 올라오면 협상 속도가 바뀌므로 operstate 변화 때만 sysfs 를 다시 읽음
+func (s *netlinkNetSource) link(iface string) linkInfo {
+	oper := s.oper[iface]
+	li, ok := s.links[iface]
+	if !ok || li.operState != oper {
+		li = readLinkSpeed(iface)
+		li.operState = oper
+		if s.links == nil {
+			s.links = map[string]linkInfo{}
+		}
+		s.links[iface] = li
+	}
+	return li
+}
+
+// RTM_GETLINK 덤프 → 인터페이스별 rtnl_link_stats64 값, operstate
+func (s *netlinkNetSource) dumpLinks() (map[string]nlLink, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_ROUTE)
+	if err != nil {
+		return nil, fmt.Errorf("netlink socket: %w", err)
+	}
+	defer unix.Close(fd)
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return nil, fmt.Errorf("netlink bind: %w", err)
+	}
+
+	s.seq++
+	req := make([]byte, unix.SizeofNlMsghdr+unix.SizeofIfInfomsg)
+	*(*unix.NlMsghdr)(unsafe.Pointer(&req[0])) = unix.NlMsghdr{
+		Len:   uint32(len(req)),
+		Type:  unix.RTM_GETLINK,
+		Flags: unix.NLM_F_REQUEST | unix.NLM_F_DUMP,
+		Seq:   s.seq,
+	}
+	*(*unix.IfInfomsg)(unsafe.Pointer(&req[unix.SizeofNlMsghdr])) = unix.IfInfomsg{Family: unix.AF_UNSPEC}
+	if err := unix.Sendto(fd, req, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return nil, fmt.Errorf("netlink send: %w", err)
+	}
+
+	out := map[string]nlLink{}
+	buf := make([]byte, 32*os.Getpagesize())
+	for {
+		n, _, err := unix.Recvfrom(fd, buf, 0)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("netlink recv: %w", err)
+		}
+		done, err := parseLinkDump(buf[:n], s.seq, out)
+		if err != nil {
+			return nil, err
+		}
+		if done {
+			return out, nil
+		}
+	}
+}
+
+func nlmAlign(n int) int { return (n + unix.NLMSG_ALIGNTO - 1) &^ (unix.NLMSG_ALIGNTO - 1) }
+
+// recv 한 번 분량의 netlink 메시지들 파싱. NLMSG_DONE 을 만나면 done
+func parseLinkDump(b []byte, seq uint32, out map[string]nlLink) (done bool, err error) {
+	for len(b) >= unix.SizeofNlMsghdr {
+		h := (*unix.NlMsghdr)(unsafe.Pointer(&b[0]))
+		l := int(h.Len)
+		if l < unix.SizeofNlMsghdr || l > len(b) {
+			return false, fmt.Errorf("netlink: bad message length %d", l)
+		}
+		msg := b[unix.SizeofNlMsghdr:l]
+		b = b[min(nlmAlign(l), len(b)):]
+		if h.Seq != seq {
+			continue // 이전 요청의 남은 응답
+		}
+		switch h.Type {
+		case unix.NLMSG_DONE:
+			return true, nil
+		case unix.NLMSG_ERROR:
+			if len(msg) >= 4 {
+				if errno := int32(binary.NativeEndian.Uint32(msg)); errno != 0 {
+					return false, fmt.Errorf("netlink: %w", unix.Errno(-errno))
+				}
+			}
+			return true, nil
+		case unix.RTM_NEWLINK:
+			if len(msg) < unix.SizeofIfInfomsg {
+				continue
+			}
+			name, link := parseLinkAttrs(msg[unix.SizeofIfInfomsg:])
+			if name != "" && link.stats != nil {
+				out[name] = link
+			}
+		}
+	}
+	return false, nil
+}
+
+// rtattr 목록에서 IFLA_IFNAME, IFLA_STATS64, IFLA_OPERSTATE 만 꺼냄
+func parseLinkAttrs(b []byte) (name string, link nlLink) {
+	for len(b) >= unix.SizeofRtAttr {
+		l := int(binary.NativeEndian.Uint16(b[0:2]))
+		typ := binary.NativeEndian.Uint16(b[2:4])
+		if l < unix.SizeofRtAttr || l > len(b) {
+			break
+		}
+		val := b[unix.SizeofRtAttr:l]
+		switch typ {
+		case unix.IFLA_IFNAME:
+			for i, c := range val {
+				if c == 0 {
+					val = val[:i]
+					break
+				}
+			}
+			name = string(val)
+		case unix.IFLA_STATS64:
+			link.stats = make(map[string]uint64, len(linkStats64Fields))
+			for i, f := range linkStats64Fields {
+				if (i+1)*8 > len(val) {
+					break
+				}
+				link.stats[f] = binary.NativeEndian.Uint64(val[i*8:])
+			}
+		case unix.IFLA_OPERSTATE:
+			if len(val) >= 1 && int(val[0]) < len(linkOperStates) {
+				link.operState = linkOperStates[val[0]]
+			}
+		}
+		b = b[min(nlmAlign(l), len(b)):]
+	}
+	return name, link
+}
@@ -0,0 +1,48 @@
+package pseudo
+
+import "testing"
+
+func benchmarkNetScan(b *testing.B, src netSource) {
+	sel, _ := NewNetSelector(nil)
+	counters := []string{"rx_packets", "tx_packets", "rx_errors", "rx_dropped", "tx_dropped", "rx_missed_errors"}
+	b.ReportAllocs()
+	for b.Loop() {
+		reads, err := src.scan(sel, counters)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for _, r := range reads {
+			src.link(r.iface)
+		}
+	}
+}
+
+// 틱 하나 분량: 모든 인터페이스의 카운터 + 링크 정보
+func BenchmarkNetScanSysfs(b *testing.B)   { benchmarkNetScan(b, sysfsNetSource{}) }
+func BenchmarkNetScanNetlink(b *testing.B) { benchmarkNetScan(b, &netlinkNetSource{}) }
+
+// 같은 인터페이스 목록과 operstate 를 보는지 (카운터 값은 읽는 시점이 달라 비교 안 함)
+func TestNetlinkMatchesSysfs(t *testing.T) {
+	sel, _ := NewNetSelector(nil)
+	nl := &netlinkNetSource{}
+	nlReads, err := nl.scan(sel, nil)
+	if err != nil {
+		t.Skipf("netlink dump: %v", err)
+	}
+	fsReads, err := sysfsNetSource{}.scan(sel, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nlReads) != len(fsReads) {
+		t.Fatalf("netlink sees %d interfaces, sysfs %d", len(nlReads), len(fsReads))
+	}
+	for i, r := range nlReads {
+		if r.iface != fsReads[i].iface {
+			t.Fatalf("interface %d: netlink %s, sysfs %s", i, r.iface, fsReads[i].iface)
+		}
+		got, want := nl.link(r.iface), readLinkInfo(r.iface)
+		if got != want {
+			t.Errorf("%s: netlink link info %+v, sysfs %+v", r.iface, got, want)
+		}
+	}
+}
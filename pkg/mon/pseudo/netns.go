@@ -0,0 +1,171 @@
+package pseudo
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const netnsRunDir = "/var/run/netns"
+
+// /proc/net/dev 컬럼 → statistics/ 파일 이름 (수신 8개, 송신 8개 순서)
+var procNetDevCols = []string{
+	"rx_bytes", "rx_packets", "rx_errors", "rx_dropped", "rx_fifo_errors", "rx_frame_errors", "rx_compressed", "multicast",
+	"tx_bytes", "tx_packets", "tx_errors", "tx_dropped", "tx_fifo_errors", "collisions", "tx_carrier_errors", "tx_compressed",
+}
+
+// 설정에 맞는 카운터 소스와 샘플 라벨. 네임스페이스 지정이 없으면 호스트 sysfs 또는 netlink
+func newNetSource(cfg NetConfig) (netSource, string, error) {
+	switch cfg.Backend {
+	case "", "sysfs", "netlink":
+	default:
+		return nil, "", fmt.Errorf("bad network backend %q", cfg.Backend)
+	}
+	if cfg.Backend == "netlink" && (cfg.Netns != "" || cfg.NetnsPID != 0) {
+		return nil, "", fmt.Errorf("netlink network backend watches the host namespace only")
+	}
+	switch {
+	case cfg.Netns != "" && cfg.NetnsPID != 0:
+		return nil, "", fmt.Errorf("netns and netns pid are mutually exclusive")
+	case cfg.Netns != "":
+		// 이름이면 라벨은 이름, 경로면 경로 그대로
+		path := cfg.Netns
+		if !strings.ContainsRune(path, '/') {
+			path = filepath.Join(netnsRunDir, path)
+		}
+		if _, err := os.Stat(path); err != nil {
+			return nil, "", fmt.Errorf("netns %s: %w", cfg.Netns, err)
+		}
+		return procNetDevSource{read: func() ([]byte, error) { return readInNetns(path, "/proc/thread-self/net/dev") }},
+			cfg.Netns, nil
+	case cfg.NetnsPID > 0:
+		path := fmt.Sprintf("/proc/%d/net/dev", cfg.NetnsPID)
+		return procNetDevSource{read: func() ([]byte, error) { return os.ReadFile(path) }},
+			"pid:" + strconv.Itoa(cfg.NetnsPID), nil
+	case cfg.NetnsPID < 0:
+		return nil, "", fmt.Errorf("bad netns pid %d", cfg.NetnsPID)
+	case cfg.Backend == "netlink":
+		return &netlinkNetSource{}, "", nil
+	}
+	return sysfsNetSource{}, "", nil
+}
+
+// nsPath 의 네트워크 네임스페이스로 들어가서 file 을 읽음
+// setns 는 스레드 단위라 전용 고루틴을 OS 스레드에 고정하고, 원래 네임스페이스로 못 돌아오면
+// 고정을 풀지 않아 고루틴 종료와 함께 그 스레드가 버려지게 함
+func readInNetns(nsPath, file string) ([]byte, error) {
+	type result struct {
+		b   []byte
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		runtime.LockOSThread()
+		b, restored, err := readInNetnsLocked(nsPath, file)
+		if restored {
+			runtime.UnlockOSThread()
+		}
+		ch <- result{b, err}
+	}()
+	r := <-ch
+	return r.b, r.err
+}
+
+func readInNetnsLocked(nsPath, file string) (b []byte, restored bool, err error) {
+	orig, err := os.Open("/proc/thread-self/ns/net")
+	if err != nil {
+		return nil, true, err
+	}
+	defer orig.Close()
+	target, err := os.Open(nsPath)
+	if err != nil {
+		return nil, true, err
+	}
+	defer target.Close()
+	if err := unix.Setns(int(target.Fd()), unix.CLONE_NEWNET); err != nil {
+		return nil, true, fmt.Errorf("setns %s: %w", nsPath, err)
+	}
+	b, err = os.ReadFile(file)
+	return b, unix.Setns(int(orig.Fd()), unix.CLONE_NEWNET) == nil, err
+}
+
+// 다른 네임스페이스: /proc/net/dev 형식을 한 번에 읽음
+type procNetDevSource struct {
+	read func() ([]byte, error)
+}
+
+func (s procNetDevSource) scan(sel *NetSelector, counters []string) ([]netRead, error) {
+	b, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	devs, err := parseProcNetDev(b)
+	if err != nil {
+		return nil, err
+	}
+	return statReads(devs, sel, counters, time.Now()), nil
+}
+
+// 인터페이스별 카운터 맵(/proc/net/dev, netlink 덤프) → 선택된 인터페이스의 netRead, 이름순
+// rx_missed_errors 처럼 그 소스에 없는 카운터는 건너뜀
+func statReads(devs map[string]map[string]uint64, sel *NetSelector, counters []string, now time.Time) []netRead {
+	out := make([]netRead, 0, len(devs))
+	for iface, vals := range devs {
+		if !sel.Match(iface) {
+			continue
+		}
+		st := netState{rx: vals["rx_bytes"], tx: vals["tx_bytes"], t: now}
+		for _, c := range counters {
+			if v, ok := vals[c]; ok {
+				if st.counters == nil {
+					st.counters = make(map[string]uint64, len(counters))
+				}
+				st.counters[c] = v
+			}
+		}
+		out = append(out, netRead{iface: iface, st: st})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].iface < out[j].iface })
+	return out
+}
+
+// 다른 네임스페이스의 sysfs 는 안 보임
+func (procNetDevSource) link(string) linkInfo { return linkInfo{} }
+
+// "  eth0: 123 4 0 ..." 줄들을 인터페이스별 카운터로 (앞의 헤더 2줄은 건너뜀)
+func parseProcNetDev(b []byte) (map[string]map[string]uint64, error) {
+	out := map[string]map[string]uint64{}
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for line := 0; sc.Scan(); line++ {
+		if line < 2 {
+			continue
+		}
+		name, rest, ok := strings.Cut(sc.Text(), ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) < len(procNetDevCols) {
+			return nil, fmt.Errorf("net/dev: short line for %s", strings.TrimSpace(name))
+		}
+		vals := make(map[string]uint64, len(procNetDevCols))
+		for i, col := range procNetDevCols {
+			v, err := strconv.ParseUint(fields[i], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("net/dev: %s %s: %w", strings.TrimSpace(name), col, err)
+			}
+			vals[col] = v
+		}
+		out[strings.TrimSpace(name)] = vals
+	}
+	return out, sc.Err()
+}
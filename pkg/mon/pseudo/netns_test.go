@@ -0,0 +1,84 @@
+package pseudo
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+const capSysAdmin = 21 // CAP_SYS_ADMIN
+
+// 전용 스레드에서 unshare(CLONE_NEWNET) 한 새 네임스페이스 (lo 만 있음)
+// 열어 둔 fd 가 네임스페이스를 붙잡고 있어서 그 스레드가 끝나도 /proc/self/fd/N 로 들어갈 수 있음
+func newTestNetns(t *testing.T) string {
+	t.Helper()
+	if !hasCap(capSysAdmin) {
+		t.Skip("needs CAP_SYS_ADMIN to create a network namespace")
+	}
+	type result struct {
+		f   *os.File
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		// 네임스페이스를 바꾼 스레드는 되돌리지 않고 고루틴과 함께 버림 (UnlockOSThread 안 함)
+		runtime.LockOSThread()
+		if err := unix.Unshare(unix.CLONE_NEWNET); err != nil {
+			ch <- result{nil, err}
+			return
+		}
+		f, err := os.Open("/proc/thread-self/ns/net")
+		ch <- result{f, err}
+	}()
+	r := <-ch
+	if r.err != nil {
+		t.Skipf("cannot create a network namespace: %v", r.err)
+	}
+	t.Cleanup(func() { r.f.Close() })
+	return fmt.Sprintf("/proc/self/fd/%d", r.f.Fd())
+}
+
+func TestReadInNetns(t *testing.T) {
+	ns := newTestNetns(t)
+	b, err := readInNetns(ns, "/proc/thread-self/net/dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	devs, err := parseProcNetDev(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devs) != 1 || devs["lo"] == nil {
+		t.Fatalf("interfaces in new netns = %v, want only lo", keys(devs))
+	}
+}
+
+func TestProcNetDevSourceNetns(t *testing.T) {
+	ns := newTestNetns(t)
+	src, label, err := newNetSource(NetConfig{Netns: ns})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if label != ns {
+		t.Errorf("label = %q, want %q", label, ns)
+	}
+	sel, _ := NewNetSelector(nil)
+	reads, err := src.scan(sel, []string{"rx_packets"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reads) != 1 || reads[0].iface != "lo" {
+		t.Fatalf("scan in new netns = %+v, want only lo", reads)
+	}
+}
+
+func keys[V any](m map[string]V) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
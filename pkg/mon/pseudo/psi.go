@@ -1,11 +1,15 @@
 package pseudo
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 
 	"context"
 	"time"
@@ -17,14 +21,13 @@ import (
 
 // PSI 스코프/경로를 유연하게
 type PSIScope struct {
-	Scope    string // "system"|"cgroup"
-	CgPath   string // cgroup 압박 파일들이 있는 디렉터리
+	Scope  string // "system"|"cgroup"
+	CgPath string // cgroup 압박 파일들이 있는 디렉터리
 }
 
-// 기본 스코프 자동 감지(실패 시 system)
+// 기본 스코프 자동 감지(실패 시 system). 자세한 점검 결과는 ProbePSI
 func DefaultPSIScope() PSIScope {
-	// 간단 버전: system으로
-	return PSIScope{Scope: "system", CgPath: "/sys/fs/cgroup"}
+	return ProbePSI().Scope
 }
 
 func psiFilePath(scope PSIScope, res string) string {
@@ -34,6 +37,55 @@ func psiFilePath(scope PSIScope, res string) string {
 	return "/proc/pressure/" + res
 }
 
+// PSI 자원 (커널에 따라 일부만 있음)
+var PSIResources = []string{"cpu", "memory", "io", "irq"}
+
+// 커널이 노출하는 PSI 자원 → 있는 줄(some/full)
+// irq 는 full 만 있고, cpu full 은 5.13 이후(시스템 단위는 항상 0)
+type PSICaps map[string][]string
+
+func (c PSICaps) Has(res, kind string) bool { return slices.Contains(c[res], kind) }
+
+// 스코프의 <res>.pressure 파일들을 읽어 실제로 있는 자원과 줄을 탐지
+func DiscoverPSI(scope PSIScope) (PSICaps, error) {
+	caps := PSICaps{}
+	var firstErr error
+	for _, res := range PSIResources {
+		kinds, err := psiKinds(psiFilePath(scope, res))
+		if err != nil {
+			if firstErr == nil && !errors.Is(err, fs.ErrNotExist) {
+				firstErr = err
+			}
+			continue
+		}
+		if len(kinds) > 0 {
+			caps[res] = kinds
+		}
+	}
+	if len(caps) == 0 {
+		if firstErr == nil {
+			firstErr = fmt.Errorf("no pressure files under %s", filepath.Dir(psiFilePath(scope, "cpu")))
+		}
+		return nil, firstErr
+	}
+	return caps, nil
+}
+
+// 파일에 있는 줄 종류 (some, full)
+func psiKinds(path string) ([]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var kinds []string
+	for _, ln := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		if k, _, ok := strings.Cut(ln, " "); ok && (k == "some" || k == "full") {
+			kinds = append(kinds, k)
+		}
+	}
+	return kinds, nil
+}
+
 func parsePSILine(line string) (avg10, avg60, avg300 float64, totalUs uint64) {
 	parts := strings.Fields(line)
 	m := map[string]string{}
@@ -73,82 +125,280 @@ func readPSIFile(path string, res string) (some T.PSIEvent, full T.PSIEvent, err
 	return
 }
 
+// 커널 PSI 트리거 하나. 한 자원에 여러 개를 걸 수 있고 각각 따로 fd 를 엶
+type PSITrigger struct {
+	Name        string // 이벤트에 붙는 이름 (예: mem-warn)
+	Severity    string // info|warn|critical
+	Res         string // cpu|memory|io|irq
+	Kind        string // some|full
+	ThresholdUs int
+	WindowUs    int
+}
+
+// 트리거 감시 중 커널이 POLLERR 를 줌 (감시하던 cgroup 이 지워짐)
+var errTriggerLost = errors.New("trigger lost")
+
 // 커널 PSI 트리거 + poll 기반 이벤트 채널
-func SpawnPSIWatcher(ctx context.Context, scope PSIScope, res, kind string, thrUs, winUs int) (<-chan T.PSIEvent, error) {
+// ctx 가 끝나면 eventfd 로 poll 을 깨워 바로 종료하고 fd 를 닫음
+// cgroup 스코프에서 cgroup 이 지워지면 State "gone" 이벤트를 내고, 같은 경로가 다시 생기면
+// (부모 디렉터리 inotify) 트리거를 다시 걸고 State "rearmed" 이벤트를 낸 뒤 계속 감시
+// 그 밖의 오류는 errs 로 알리고 이벤트 채널을 닫음
+func SpawnPSIWatcher(ctx context.Context, scope PSIScope, tr PSITrigger) (<-chan T.PSIEvent, <-chan error, error) {
 	out := make(chan T.PSIEvent, 16)
+	errs := make(chan error, 1)
 
-	path := psiFilePath(scope, res)
-	fd, err := unix.Open(path, unix.O_RDWR|unix.O_NONBLOCK, 0)
+	path := psiFilePath(scope, tr.Res)
+	// 없는 줄에 트리거를 쓰면 EINVAL 만 오므로 미리 확인 (irq 는 full 만)
+	kinds, err := psiKinds(path)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	// 트리거 쓰기
-	trig := fmt.Sprintf("%s %d %d\n", kind, thrUs, winUs)
-	if _, err := unix.Write(fd, []byte(trig)); err != nil {
+	if !slices.Contains(kinds, tr.Kind) {
+		return nil, nil, fmt.Errorf("psi %s has no %q line (available: %s)", path, tr.Kind, strings.Join(kinds, ","))
+	}
+	fd, err := armPSITrigger(path, tr)
+	if err != nil {
+		return nil, nil, err
+	}
+	wake, err := newWakeFd(ctx)
+	if err != nil {
 		_ = unix.Close(fd)
-		return nil, err
+		return nil, nil, err
 	}
 
 	go func() {
-		defer unix.Close(fd)
+		defer wake.close()
+		defer close(errs)
 		defer close(out)
-		pfd := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLPRI}}
-
-		for {
-			// ctx 취소 처리
+		// 상태 이벤트는 드랍하지 않음
+		lifecycle := func(state string) bool {
+			ev := T.PSIEvent{Res: tr.Res, Kind: tr.Kind, Trigger: tr.Name, Severity: tr.Severity,
+				Threshold: tr.ThresholdUs, Window: tr.WindowUs, State: state, Ts: T.NowMS()}
 			select {
+			case out <- ev:
+				return true
 			case <-ctx.Done():
+				return false
+			}
+		}
+		for {
+			err := watchPSITrigger(ctx, fd, wake, path, tr, out)
+			_ = unix.Close(fd)
+			if ctx.Err() != nil {
 				return
-			default:
 			}
-
-			_, err := unix.Poll(pfd, -1) // 1s 타임아웃(취소 체크용)
-			if err != nil { return }
-			re := pfd[0].Revents
-			if re&(unix.POLLERR|unix.POLLNVAL) != 0 {
+			if !errors.Is(err, errTriggerLost) || scope.Scope != "cgroup" {
+				errs <- err
 				return
 			}
-			if re&unix.POLLPRI != 0 {
-				s, f, _ := readPSIFile(path, res)
-				ev := s
-				if kind == "full" {
-					ev = f
-				}
-				ev.Kind = kind
-				ev.Threshold = thrUs
-				ev.Window = winUs
-				select {
-				case out <- ev:
-				default: // 채널 가득이면 드랍(최신값 우선)
-				}
+			if !lifecycle("gone") {
+				return
+			}
+			fd, err = waitPSIRearm(ctx, wake, path, tr)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				errs <- err
+				return
+			}
+			if !lifecycle("rearmed") {
+				_ = unix.Close(fd)
+				return
 			}
 		}
 	}()
-	return out, nil
+	return out, errs, nil
+}
+
+// 압박 파일을 열고 트리거를 씀. 파일이 없으면 fs.ErrNotExist 로 구분 가능
+func armPSITrigger(path string, tr PSITrigger) (int, error) {
+	fd, err := unix.Open(path, unix.O_RDWR|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return -1, fmt.Errorf("psi %s: %w", path, err)
+	}
+	trig := fmt.Sprintf("%s %d %d\n", tr.Kind, tr.ThresholdUs, tr.WindowUs)
+	if _, err := unix.Write(fd, []byte(trig)); err != nil {
+		_ = unix.Close(fd)
+		return -1, fmt.Errorf("psi trigger %q on %s: %w", strings.TrimSpace(trig), path, err)
+	}
+	return fd, nil
+}
+
+// 트리거가 울릴 때마다 이벤트를 보냄. ctx 가 끝나면 nil, 트리거를 잃으면 errTriggerLost
+func watchPSITrigger(ctx context.Context, fd int, wake *wakeFd, path string, tr PSITrigger, out chan<- T.PSIEvent) error {
+	pfd := []unix.PollFd{
+		{Fd: int32(fd), Events: unix.POLLPRI},
+		{Fd: int32(wake.fd), Events: unix.POLLIN},
+	}
+	for {
+		_, err := unix.Poll(pfd, -1)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("psi %s poll: %w", path, err)
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		re := pfd[0].Revents
+		if re&(unix.POLLERR|unix.POLLNVAL) != 0 {
+			// 감시하던 cgroup 이 지워지면 커널이 POLLERR 를 줌
+			return fmt.Errorf("psi %s: %w (cgroup removed?)", path, errTriggerLost)
+		}
+		if re&unix.POLLPRI != 0 {
+			s, f, _ := readPSIFile(path, tr.Res)
+			ev := s
+			if tr.Kind == "full" {
+				ev = f
+			}
+			ev.Kind = tr.Kind
+			ev.Threshold = tr.ThresholdUs
+			ev.Window = tr.WindowUs
+			ev.Trigger = tr.Name
+			ev.Severity = tr.Severity
+			select {
+			case out <- ev:
+			default: // 채널 가득이면 드랍(최신값 우선)
+			}
+		}
+	}
+}
+
+// 지워진 cgroup 디렉터리가 다시 생길 때까지 부모 디렉터리를 inotify 로 보다가 트리거를 다시 검
+// ctx 가 끝나면 (-1, nil). 부모까지 사라지면 오류
+func waitPSIRearm(ctx context.Context, wake *wakeFd, path string, tr PSITrigger) (int, error) {
+	parent := filepath.Dir(filepath.Dir(path))
+	ifd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return -1, fmt.Errorf("inotify: %w", err)
+	}
+	defer unix.Close(ifd)
+	if _, err := unix.InotifyAddWatch(ifd, parent, unix.IN_CREATE|unix.IN_ONLYDIR); err != nil {
+		return -1, fmt.Errorf("psi %s: watch %s: %w", path, parent, err)
+	}
+	pfd := []unix.PollFd{
+		{Fd: int32(ifd), Events: unix.POLLIN},
+		{Fd: int32(wake.fd), Events: unix.POLLIN},
+	}
+	buf := make([]byte, 4096)
+	for {
+		// 감시를 건 뒤에 확인해야 그 사이 생성을 놓치지 않음
+		fd, err := armPSITrigger(path, tr)
+		if err == nil {
+			return fd, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return -1, err
+		}
+		if _, err := os.Stat(parent); err != nil {
+			return -1, fmt.Errorf("psi %s: parent cgroup gone: %w", path, err)
+		}
+		for {
+			_, err := unix.Poll(pfd, -1)
+			if err == unix.EINTR {
+				continue
+			}
+			if err != nil {
+				return -1, fmt.Errorf("psi %s poll: %w", path, err)
+			}
+			break
+		}
+		if ctx.Err() != nil {
+			return -1, nil
+		}
+		// 형제 cgroup 생성일 수도 있으니 이벤트 내용은 보지 않고 비우기만 하고 다시 시도
+		for {
+			if _, err := unix.Read(ifd, buf); err != nil && err != unix.EINTR {
+				break
+			}
+		}
+	}
+}
+
+// ctx 가 끝나면 읽을 수 있게 되는 eventfd. poll 집합에 넣어 블로킹 poll 을 깨우는 용도
+type wakeFd struct {
+	fd     int
+	mu     sync.Mutex
+	closed bool
+	stop   func() bool
+}
+
+func newWakeFd(ctx context.Context) (*wakeFd, error) {
+	fd, err := unix.Eventfd(0, unix.EFD_CLOEXEC|unix.EFD_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("eventfd: %w", err)
+	}
+	w := &wakeFd{fd: fd}
+	w.stop = context.AfterFunc(ctx, func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if !w.closed {
+			var one [8]byte
+			one[0] = 1 // eventfd 는 호스트 바이트 순서 u64, 0이 아니면 됨
+			_, _ = unix.Write(w.fd, one[:])
+		}
+	})
+	return w, nil
+}
+
+// AfterFunc 가 닫힌(재사용됐을 수도 있는) fd 에 쓰지 않도록 잠금 안에서 닫음
+func (w *wakeFd) close() {
+	w.stop()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	_ = unix.Close(w.fd)
 }
 
 // 일정 주기로 /proc/pressure/*를 읽어 최신 Avg10을 보장하는 간단 폴러
+// total 차이로 폴링 간격 동안의 stall 비율(StallFrac)을 계산해 some, full(있을 때) 순서로 내보냄
+// avg10 보다 빠른 신호. 첫 틱과 total 이 줄어든 틱(cgroup 재생성)은 건너뜀
 func SpawnPSIPoller(ctx context.Context, scope PSIScope, res string, every time.Duration) <-chan T.PSIEvent {
-	out := make(chan T.PSIEvent, 1)
+	out := make(chan T.PSIEvent, 2)
 	path := psiFilePath(scope, res)
 	go func() {
 		defer close(out)
 		t := time.NewTicker(every)
 		defer t.Stop()
+		var prevSome, prevFull T.PSIEvent
+		var prevT time.Time
 		for {
 			select {
 			case <-ctx.Done():
 				return
 			case <-t.C:
-				s, _, err := readPSIFile(path, res)
-				if err == nil {
-					select {
-					case out <- s:
-					default:
+				s, f, err := readPSIFile(path, res)
+				now := time.Now()
+				if err != nil {
+					prevT = time.Time{}
+					continue
+				}
+				if !prevT.IsZero() {
+					dt := now.Sub(prevT)
+					for _, ev := range []T.PSIEvent{stallFrac(s, prevSome, dt), stallFrac(f, prevFull, dt)} {
+						if ev.Kind == "" {
+							continue
+						}
+						select {
+						case out <- ev:
+						default:
+						}
 					}
 				}
+				prevSome, prevFull, prevT = s, f, now
 			}
 		}
 	}()
 	return out
 }
+
+// cur 에 prev 이후 dt 동안의 stall 비율을 채움. 계산할 수 없으면 Kind 가 빈 이벤트
+func stallFrac(cur, prev T.PSIEvent, dt time.Duration) T.PSIEvent {
+	if cur.Kind == "" || prev.Kind == "" || cur.TotalUs < prev.TotalUs || dt <= 0 {
+		return T.PSIEvent{}
+	}
+	cur.StallFrac = min(float64(cur.TotalUs-prev.TotalUs)/float64(dt.Microseconds()), 1)
+	return cur
+}
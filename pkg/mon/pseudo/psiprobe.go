@@ -0,0 +1,215 @@
+package pseudo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"resmon/pkg/cgroupfs"
+)
+
+const (
+	procCgroupInitIno = 0xEFFFFFFB // 초기 cgroup 네임스페이스의 고정 inode (PROC_CGROUP_INIT_INO)
+	capSysResource    = 24         // CAP_SYS_RESOURCE
+	psiUnprivWindow   = 2 * time.Second
+)
+
+// 시작 시 PSI 지원 여부 점검 결과
+type PSIReport struct {
+	ProcPressure bool   // /proc/pressure 존재
+	Disabled     bool   // 커널 cmdline 에 psi=0
+	Cgroup2Mount string // cgroup2 마운트 지점 (없으면 빈 값)
+	CgroupNS     bool   // 초기 cgroup 네임스페이스가 아님 (컨테이너 등)
+	OwnCgroup    string // 자기 cgroup 디렉터리 (cgroup2 마운트 아래)
+
+	Privileged bool          // CAP_SYS_RESOURCE (트리거 창 제한 없음)
+	Triggers   bool          // 시험 트리거 쓰기 성공
+	WindowStep time.Duration // 트리거 창이 이 값의 배수여야 함 (비특권 2s, 제한 없으면 0)
+	TriggerErr error         // 트리거를 못 쓰는 이유
+
+	Caps  PSICaps  // Scope 에서 보이는 자원/줄
+	Scope PSIScope // 고른 기본 스코프
+}
+
+// PSI 관련 커널/환경 점검. 결과로 기본 스코프를 고름
+//   - 컨테이너(cgroup 네임스페이스) 안: /proc/pressure 는 호스트 전체라 자기 cgroup 의 압박 파일
+//   - 그 밖: /proc/pressure, 없으면 자기 cgroup
+func ProbePSI() PSIReport {
+	var r PSIReport
+	_, err := os.Stat("/proc/pressure")
+	r.ProcPressure = err == nil
+	r.Disabled = psiDisabledOnCmdline()
+	r.Cgroup2Mount = cgroupfs.Cgroup2Mount()
+	var st unix.Stat_t
+	if err := unix.Stat("/proc/self/ns/cgroup", &st); err == nil {
+		r.CgroupNS = st.Ino != procCgroupInitIno
+	}
+	if r.Cgroup2Mount != "" {
+		if cg := ownCgroup2Path(); cg != "" {
+			r.OwnCgroup = filepath.Join(r.Cgroup2Mount, cg)
+		}
+	}
+	r.Privileged = hasCap(capSysResource)
+
+	system := PSIScope{Scope: "system", CgPath: "/sys/fs/cgroup"}
+	own := PSIScope{Scope: "cgroup", CgPath: r.OwnCgroup}
+	ownCaps := PSICaps(nil)
+	if r.OwnCgroup != "" {
+		ownCaps, _ = DiscoverPSI(own)
+	}
+	sysCaps := PSICaps(nil)
+	if r.ProcPressure {
+		sysCaps, _ = DiscoverPSI(system)
+	}
+	switch {
+	case r.CgroupNS && len(ownCaps) > 0:
+		r.Scope, r.Caps = own, ownCaps
+	case len(sysCaps) > 0:
+		r.Scope, r.Caps = system, sysCaps
+	case len(ownCaps) > 0:
+		r.Scope, r.Caps = own, ownCaps
+	default:
+		r.Scope = system
+	}
+	r.probeTriggers()
+	return r
+}
+
+// 고른 스코프에 시험 트리거를 써 봄 (fd 를 닫으면 트리거도 사라짐)
+// 1s 창이 거부되고 2s 창이 되면 비특권 제한 (창은 2s 배수)
+func (r *PSIReport) probeTriggers() {
+	if len(r.Caps) == 0 {
+		r.TriggerErr = errors.New("no pressure files")
+		return
+	}
+	res := "cpu"
+	if _, ok := r.Caps[res]; !ok {
+		for k := range r.Caps {
+			res = k
+			break
+		}
+	}
+	tr := PSITrigger{Res: res, Kind: r.Caps[res][0], ThresholdUs: 100000}
+	path := psiFilePath(r.Scope, res)
+	for _, win := range []time.Duration{time.Second, psiUnprivWindow} {
+		tr.WindowUs = int(win.Microseconds())
+		fd, err := armPSITrigger(path, tr)
+		if err == nil {
+			_ = unix.Close(fd)
+			r.Triggers = true
+			if win == psiUnprivWindow {
+				r.WindowStep = psiUnprivWindow
+			}
+			return
+		}
+		r.TriggerErr = err
+		if !errors.Is(err, unix.EINVAL) {
+			return // EPERM(예전 커널의 비특권) 등은 창을 바꿔도 안 됨
+		}
+	}
+}
+
+// 트리거 창이 이 환경에서 허용되는지
+func (r PSIReport) WindowOK(windowUs int) bool {
+	return r.WindowStep == 0 || windowUs%int(r.WindowStep.Microseconds()) == 0
+}
+
+// 허용되지 않는 창은 WindowStep 배수로 올리고 임계값도 같은 비율로 늘림 (압박 비율 유지)
+func (r PSIReport) FitTrigger(tr PSITrigger) PSITrigger {
+	if r.WindowOK(tr.WindowUs) || tr.WindowUs <= 0 {
+		return tr
+	}
+	step := int(r.WindowStep.Microseconds())
+	win := (tr.WindowUs + step - 1) / step * step
+	tr.ThresholdUs = int(int64(tr.ThresholdUs) * int64(win) / int64(tr.WindowUs))
+	tr.WindowUs = win
+	return tr
+}
+
+func (r PSIReport) String() string {
+	yn := func(b bool) string {
+		if b {
+			return "yes"
+		}
+		return "no"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "PSI: /proc/pressure=%s psi=0=%s cgroup2=%q cgroupns=%s own=%q\n",
+		yn(r.ProcPressure), yn(r.Disabled), r.Cgroup2Mount, yn(r.CgroupNS), r.OwnCgroup)
+	fmt.Fprint(&b, "PSI: resources")
+	for _, res := range PSIResources {
+		if kinds, ok := r.Caps[res]; ok {
+			fmt.Fprintf(&b, " %s(%s)", res, strings.Join(kinds, ","))
+		}
+	}
+	if len(r.Caps) == 0 {
+		fmt.Fprint(&b, " none")
+	}
+	fmt.Fprintln(&b)
+	switch {
+	case !r.Triggers:
+		fmt.Fprintf(&b, "PSI: triggers unavailable (privileged=%s): %v\n", yn(r.Privileged), r.TriggerErr)
+	case r.WindowStep > 0:
+		fmt.Fprintf(&b, "PSI: triggers ok, unprivileged (window must be a multiple of %s)\n", r.WindowStep)
+	default:
+		fmt.Fprintf(&b, "PSI: triggers ok (privileged=%s)\n", yn(r.Privileged))
+	}
+	if r.Scope.Scope == "cgroup" {
+		fmt.Fprintf(&b, "PSI: default scope cgroup %s", r.Scope.CgPath)
+	} else {
+		fmt.Fprint(&b, "PSI: default scope system")
+	}
+	return b.String()
+}
+
+// psi=0 (kstrtobool 거짓 값)으로 부팅했는지
+func psiDisabledOnCmdline() bool {
+	b, err := os.ReadFile("/proc/cmdline")
+	if err != nil {
+		return false
+	}
+	for _, f := range strings.Fields(string(b)) {
+		if v, ok := strings.CutPrefix(f, "psi="); ok {
+			switch strings.ToLower(v) {
+			case "0", "n", "no", "off":
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// /proc/self/cgroup 의 "0::<path>" (cgroup v2 경로, 네임스페이스 안이면 그 루트 기준)
+func ownCgroup2Path() string {
+	b, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return ""
+	}
+	for _, ln := range strings.Split(string(b), "\n") {
+		if p, ok := strings.CutPrefix(ln, "0::"); ok {
+			return p
+		}
+	}
+	return ""
+}
+
+// 유효 capability 에 cap 이 있는지 (/proc/self/status 의 CapEff)
+func hasCap(cap uint) bool {
+	b, err := os.ReadFile("/proc/self/status")
+	if err != nil {
+		return false
+	}
+	for _, ln := range strings.Split(string(b), "\n") {
+		if v, ok := strings.CutPrefix(ln, "CapEff:"); ok {
+			mask, err := strconv.ParseUint(strings.TrimSpace(v), 16, 64)
+			return err == nil && mask&(1<<cap) != 0
+		}
+	}
+	return false
+}
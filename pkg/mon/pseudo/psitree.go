@@ -0,0 +1,210 @@
+package pseudo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+
+	"resmon/pkg/cgroupfs"
+	T "resmon/pkg/types"
+)
+
+// cgroup 서브트리 PSI 폴러 설정
+type PSITreeConfig struct {
+	Root      string   // 서브트리 루트 디렉터리 (예: /sys/fs/cgroup/kubepods.slice), 루트 자신도 포함
+	Resources []string // 기본 PSIResources (없는 자원은 건너뜀)
+	Interval  time.Duration
+}
+
+// 서브트리의 cgroup 목록 + inotify 로 생성/삭제 추적
+type cgroupTree struct {
+	mount string // cgroup2 마운트 (라벨 기준, perf 의 상대 cgroup 경로와 같은 cgroupfs.Root())
+	ifd   int
+	wds   map[int]string // inotify wd → 디렉터리
+	dirs  map[string]int // 디렉터리 → wd
+}
+
+// 라벨: 마운트 기준 경로 (/proc/<pid>/cgroup 과 같은 형태)
+func (t *cgroupTree) label(dir string) string {
+	rel, err := filepath.Rel(t.mount, dir)
+	if err != nil || rel == "." {
+		return "/"
+	}
+	return "/" + rel
+}
+
+func newCgroupTree(root string) (*cgroupTree, error) {
+	root = filepath.Clean(root)
+	if _, err := os.Stat(filepath.Join(root, "cgroup.controllers")); err != nil {
+		return nil, fmt.Errorf("%s is not a cgroup v2 directory: %w", root, err)
+	}
+	ifd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("inotify: %w", err)
+	}
+	t := &cgroupTree{mount: cgroupfs.Root(), ifd: ifd, wds: map[int]string{}, dirs: map[string]int{}}
+	t.addTree(root)
+	return t, nil
+}
+
+func (t *cgroupTree) close() { unix.Close(t.ifd) }
+
+// dir 과 하위 디렉터리 전부 추가. 감시를 먼저 걸고 나서 읽어야 그 사이 생성분을 놓치지 않음
+func (t *cgroupTree) addTree(dir string) {
+	if _, ok := t.dirs[dir]; ok {
+		return
+	}
+	wd, err := unix.InotifyAddWatch(t.ifd, dir, unix.IN_CREATE|unix.IN_DELETE|unix.IN_ONLYDIR)
+	if err != nil {
+		return // 이미 사라짐
+	}
+	t.wds[wd] = dir
+	t.dirs[dir] = wd
+	ents, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, e := range ents {
+		if e.IsDir() {
+			t.addTree(filepath.Join(dir, e.Name()))
+		}
+	}
+}
+
+// dir 과 그 하위를 목록에서 뺌 (rmdir 된 cgroup 은 비어 있어야 하지만 이벤트 순서는 보장 안 됨)
+func (t *cgroupTree) removeTree(dir string) {
+	for d, wd := range t.dirs {
+		if d == dir || strings.HasPrefix(d, dir+"/") {
+			_, _ = unix.InotifyRmWatch(t.ifd, uint32(wd))
+			delete(t.dirs, d)
+			delete(t.wds, wd)
+		}
+	}
+}
+
+// 쌓인 inotify 이벤트 반영 (논블로킹)
+func (t *cgroupTree) sync(root string) error {
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := unix.Read(t.ifd, buf)
+		if errors.Is(err, unix.EAGAIN) {
+			return nil
+		}
+		if errors.Is(err, unix.EINTR) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("inotify read: %w", err)
+		}
+		for off := 0; off+unix.SizeofInotifyEvent <= n; {
+			ev := (*unix.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			nameBytes := buf[off+unix.SizeofInotifyEvent : off+unix.SizeofInotifyEvent+int(ev.Len)]
+			off += unix.SizeofInotifyEvent + int(ev.Len)
+
+			if ev.Mask&unix.IN_Q_OVERFLOW != 0 {
+				// 이벤트를 잃었으니 처음부터 다시 훑음
+				t.removeTree(root)
+				t.addTree(root)
+				continue
+			}
+			parent, ok := t.wds[int(ev.Wd)]
+			if ev.Mask&unix.IN_IGNORED != 0 {
+				if ok {
+					delete(t.dirs, parent)
+					delete(t.wds, int(ev.Wd))
+				}
+				continue
+			}
+			if !ok || ev.Mask&unix.IN_ISDIR == 0 {
+				continue
+			}
+			dir := filepath.Join(parent, strings.TrimRight(string(nameBytes), "\x00"))
+			switch {
+			case ev.Mask&unix.IN_CREATE != 0:
+				t.addTree(dir)
+			case ev.Mask&unix.IN_DELETE != 0:
+				t.removeTree(dir)
+			}
+		}
+	}
+}
+
+// 서브트리의 모든 cgroup 에서 <res>.pressure 를 주기적으로 읽어 Cgroup 라벨을 붙여 내보냄
+// some 과 full(있을 때)을 각각 이벤트로. 생성/삭제되는 cgroup 은 inotify 로 따라감
+// 한 틱 분량이 많을 수 있어 드랍하지 않고 소비자를 기다림
+// inotify 오류로 트리를 못 따라가면 errs 로 알리고 이벤트 채널을 닫음
+func SpawnPSITreePoller(ctx context.Context, cfg PSITreeConfig) (<-chan T.PSIEvent, <-chan error, error) {
+	res := cfg.Resources
+	if len(res) == 0 {
+		res = PSIResources
+	}
+	root := filepath.Clean(cfg.Root)
+	tree, err := newCgroupTree(root)
+	if err != nil {
+		return nil, nil, err
+	}
+	out := make(chan T.PSIEvent, 64)
+	errs := make(chan error, 1)
+	go func() {
+		defer tree.close()
+		defer close(errs)
+		defer close(out)
+		send := func(ev T.PSIEvent) bool {
+			select {
+			case out <- ev:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		t := time.NewTicker(cfg.Interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+			if err := tree.sync(root); err != nil {
+				errs <- fmt.Errorf("psi tree %s: %w", root, err)
+				return
+			}
+			dirs := make([]string, 0, len(tree.dirs))
+			for dir := range tree.dirs {
+				dirs = append(dirs, dir)
+			}
+			sort.Strings(dirs)
+			for _, dir := range dirs {
+				cg := tree.label(dir)
+				for _, r := range res {
+					some, full, err := readPSIFile(filepath.Join(dir, r+".pressure"), r)
+					if err != nil {
+						continue // 방금 삭제됨 (다음 sync 에서 빠짐)
+					}
+					// irq 처럼 한쪽 줄만 있는 자원도 있음
+					if some.Kind != "" {
+						some.Cgroup = cg
+						if !send(some) {
+							return
+						}
+					}
+					if full.Kind != "" {
+						full.Cgroup = cg
+						if !send(full) {
+							return
+						}
+					}
+				}
+			}
+		}
+	}()
+	return out, errs, nil
+}
@@ -0,0 +1,164 @@
+package pseudo
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	T "resmon/pkg/types"
+)
+
+// 프로토콜 카운터 파일. 둘 다 "Proto: 이름들" / "Proto: 값들" 두 줄씩
+var protoFiles = []string{"/proc/net/snmp", "/proc/net/netstat"}
+
+// 기본 카운터: 재전송, 리셋, listen 큐 넘침/드랍, UDP 버퍼 부족
+var DefaultProtoCounters = []string{
+	"Tcp.InSegs",
+	"Tcp.OutSegs",
+	"Tcp.RetransSegs",
+	"Tcp.EstabResets",
+	"Tcp.OutRsts",
+	"Tcp.AttemptFails",
+	"TcpExt.ListenOverflows",
+	"TcpExt.ListenDrops",
+	"TcpExt.TCPTimeouts",
+	"TcpExt.TCPLostRetransmit",
+	"Udp.InErrors",
+	"Udp.RcvbufErrors",
+	"Udp.SndbufErrors",
+	"Udp.NoPorts",
+}
+
+// 프로토콜 카운터 감시 설정
+type ProtoConfig struct {
+	Interval time.Duration
+	Counters []string // "Proto.Field" (Tcp.RetransSegs, TcpExt.ListenOverflows, ...), 비면 DefaultProtoCounters
+}
+
+// /proc/net/snmp, /proc/net/netstat → "Proto.Field" 값
+// Tcp.MaxConn 처럼 음수인 값도 있어서 int64
+func readProtoCounters() (map[string]int64, error) {
+	out := map[string]int64{}
+	for _, p := range protoFiles {
+		if err := parseProtoFile(p, out); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func parseProtoFile(path string, out map[string]int64) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024) // TcpExt 줄은 김
+	var header []string
+	for sc.Scan() {
+		proto, rest, ok := strings.Cut(sc.Text(), ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(rest)
+		// 같은 프로토콜의 첫 줄은 이름, 다음 줄은 값
+		if header == nil || header[0] != proto {
+			header = append([]string{proto}, fields...)
+			continue
+		}
+		names := header[1:]
+		for i, v := range fields {
+			if i >= len(names) {
+				break
+			}
+			if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+				out[proto+"."+names[i]] = n
+			}
+		}
+		header = nil
+	}
+	return sc.Err()
+}
+
+// TCP/UDP 카운터의 인터벌당 초당 비율을 내보냄
+// 설정한 카운터가 이 커널에 없으면 시작 시 에러
+func SpawnProtoWatcher(ctx context.Context, cfg ProtoConfig) (<-chan T.ProtoSample, error) {
+	counters := cfg.Counters
+	if len(counters) == 0 {
+		counters = DefaultProtoCounters
+	}
+	prev, err := readProtoCounters()
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	for _, c := range counters {
+		if _, ok := prev[c]; !ok {
+			missing = append(missing, c)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("protocol counters not found in %s: %s", strings.Join(protoFiles, ", "), strings.Join(missing, ","))
+	}
+
+	out := make(chan T.ProtoSample, 8)
+	go func() {
+		defer close(out)
+		prevT := time.Now()
+		t := time.NewTicker(cfg.Interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				ps := T.ProtoSample{Ts: T.NowMS()}
+				cur, err := readProtoCounters()
+				now := time.Now()
+				if err != nil {
+					ps.Invalid, ps.Err = true, err.Error()
+				} else {
+					ps.Rates = protoRates(cur, prev, counters, now.Sub(prevT).Seconds())
+					ps.RetransRatio = retransRatio(cur, prev)
+					prev, prevT = cur, now
+				}
+				select {
+				case out <- ps:
+				default:
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+// 줄어든 카운터(네임스페이스 재생성 등)는 그 인터벌에서 뺌
+func protoRates(cur, prev map[string]int64, counters []string, dt float64) map[string]float64 {
+	if dt <= 0 {
+		return nil
+	}
+	rates := make(map[string]float64, len(counters))
+	for _, c := range counters {
+		v, ok1 := cur[c]
+		p, ok2 := prev[c]
+		if ok1 && ok2 && v >= p {
+			rates[c] = float64(v-p) / dt
+		}
+	}
+	return rates
+}
+
+// 인터벌 동안 보낸 세그먼트 중 재전송 비율
+func retransRatio(cur, prev map[string]int64) float64 {
+	out := cur["Tcp.OutSegs"] - prev["Tcp.OutSegs"]
+	re := cur["Tcp.RetransSegs"] - prev["Tcp.RetransSegs"]
+	if out <= 0 || re < 0 {
+		return 0
+	}
+	return float64(re) / float64(out)
+}
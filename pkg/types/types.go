@@ -5,41 +5,109 @@ import "time"
 // 공용 타입들
 
 type PSIEvent struct {
-	Res       string  `json:"res"` // cpu|memory|io
-	Kind      string  `json:"kind"` // some|full
+	Res       string  `json:"res"`                // cpu|memory|io|irq
+	Kind      string  `json:"kind"`               // some|full
+	Trigger   string  `json:"trigger,omitempty"`  // 트리거 이름 (트리거 이벤트일 때)
+	Severity  string  `json:"severity,omitempty"` // info|warn|critical
 	Threshold int     `json:"thr_us"`
 	Window    int     `json:"win_us"`
 	Ts        int64   `json:"ts_unix_ms"`
-	Avg10     float64 `json:"avg10"`  // NOTE: /proc 값 그대로(%) → 사용하는 쪽에서 /100 정규화 권장
+	Avg10     float64 `json:"avg10"` // NOTE: /proc 값 그대로(%) → 사용하는 쪽에서 /100 정규화 권장
 	Avg60     float64 `json:"avg60"`
 	Avg300    float64 `json:"avg300"`
 	TotalUs   uint64  `json:"total_us"`
+	StallFrac float64 `json:"stall_frac,omitempty"` // 폴링 간격 동안 stall 비율 (0..1, total 차이로 계산)
+	Cgroup    string  `json:"cgroup,omitempty"`     // 서브트리 감시일 때 cgroup 경로 (마운트 기준)
+	State     string  `json:"state,omitempty"`      // 트리거 상태 이벤트: gone(cgroup 삭제됨)|rearmed(다시 걸림), 샘플이면 빈 값
 }
 
 type NetSample struct {
 	Iface string `json:"iface"`
+	Netns string `json:"netns,omitempty"` // 다른 네임스페이스면 이름 또는 pid:<pid>
 	RxBps uint64 `json:"rx_bps"`
 	TxBps uint64 `json:"tx_bps"`
-	Ts    int64  `json:"ts_unix_ms"`
+	// 설정된 statistics/ 카운터의 초당 비율 (rx_packets, rx_dropped, ...)
+	Rates map[string]float64 `json:"rates,omitempty"`
+	// 링크 상태. SpeedMbps 는 utilization 계산에 쓴 용량 (speed 를 못 읽으면 설정된 nominal 값, 둘 다 없으면 0)
+	SpeedMbps int64   `json:"speed_mbps,omitempty"`
+	Duplex    string  `json:"duplex,omitempty"`    // full|half|unknown
+	OperState string  `json:"operstate,omitempty"` // up|down|dormant|unknown ...
+	RxUtil    float64 `json:"rx_util"`             // 용량 대비 비율 (0~1), 용량을 모르면 0
+	TxUtil    float64 `json:"tx_util"`
+	// 큐별 비율 (ethtool 드라이버 통계, 켜져 있고 드라이버가 지원할 때만)
+	Queues []NetQueue `json:"queues,omitempty"`
+	// 리셋을 건넌 구간이거나 읽기 실패면 true (비율 값은 0이지만 측정값 아님)
+	Invalid bool   `json:"invalid,omitempty"`
+	Err     string `json:"error,omitempty"` // 카운터 읽기 실패 원인
+	Ts      int64  `json:"ts_unix_ms"`
+}
+
+type NetQueue struct {
+	Dir   string  `json:"dir"` // rx|tx
+	Queue int     `json:"queue"`
+	Pps   float64 `json:"pps"`
+	Bps   float64 `json:"bps"`
+}
+
+// /proc/net/snmp, /proc/net/netstat 카운터의 인터벌 비율
+type ProtoSample struct {
+	Rates        map[string]float64 `json:"rates"`         // "Tcp.RetransSegs" → 초당 증가량
+	RetransRatio float64            `json:"retrans_ratio"` // RetransSegs / OutSegs (인터벌 기준)
+	Invalid      bool               `json:"invalid,omitempty"`
+	Err          string             `json:"error,omitempty"`
+	Ts           int64              `json:"ts_unix_ms"`
 }
 
 type MemBw struct {
-	Source   string  `json:"source"` // perf
-	ReadMBs  float64 `json:"read_mbps"`
-	WriteMBs float64 `json:"write_mbps"`
-	TotalMBs float64 `json:"total_mbps"`
-	Ts       int64   `json:"ts_unix_ms"`
+	Source        string  `json:"source"` // perf
+	ReadMBs       float64 `json:"read_mbps"`
+	WriteMBs      float64 `json:"write_mbps"`
+	TotalMBs      float64 `json:"total_mbps"`
+	Ts            int64   `json:"ts_unix_ms"`
+	Scope         string  `json:"scope"`      // system|socketN|nodeN
+	Confidence    float64 `json:"confidence"` // 멀티플렉싱 비율(running/enabled), 1=전 구간 카운팅
+	LowConfidence bool    `json:"low_confidence,omitempty"`
 }
 
 type LLCSample struct {
-	MPKI   float64 `json:"mpki"`
-	HitRate float64 `json:"hit_rate"`
-	Loads  uint64  `json:"loads"`
-	Stores uint64  `json:"stores"`
-	Misses uint64  `json:"misses"` // load+store misses
-	Instr  uint64  `json:"instructions"`
-	Ts     int64   `json:"ts_unix_ms"`
-	Source string  `json:"source"` // perf
+	MPKI          float64 `json:"mpki"`
+	HitRate       float64 `json:"hit_rate"`
+	Loads         uint64  `json:"loads"`
+	Stores        uint64  `json:"stores"`
+	Misses        uint64  `json:"misses"` // load+store misses
+	Instr         uint64  `json:"instructions"`
+	Ts            int64   `json:"ts_unix_ms"`
+	Source        string  `json:"source"`           // perf
+	Scope         string  `json:"scope"`            // system|cpuN|socketN|nodeN
+	Cgroup        string  `json:"cgroup,omitempty"` // cgroup 단위 카운팅이면 cgroup 경로
+	Confidence    float64 `json:"confidence"`       // 멀티플렉싱 비율(running/enabled), 1=전 구간 카운팅
+	LowConfidence bool    `json:"low_confidence,omitempty"`
+}
+
+// perf 파생 메트릭 하나 (빌트인 + derived_metrics)
+type MetricSample struct {
+	Name          string  `json:"name"`
+	Value         float64 `json:"value"`
+	Scope         string  `json:"scope"` // system|cpuN|socketN|nodeN
+	Cgroup        string  `json:"cgroup,omitempty"`
+	Confidence    float64 `json:"confidence"`
+	LowConfidence bool    `json:"low_confidence,omitempty"`
+	Ts            int64   `json:"ts_unix_ms"`
+	Source        string  `json:"source"` // perf
+}
+
+// TMA level 1: 파이프라인 slot 비율 (합 ≈ 1)
+type TopDownSample struct {
+	FrontendBound  float64 `json:"frontend_bound"`
+	BackendBound   float64 `json:"backend_bound"`
+	BadSpeculation float64 `json:"bad_speculation"`
+	Retiring       float64 `json:"retiring"`
+	Mode           string  `json:"mode"`  // perf-metrics|legacy
+	Scope          string  `json:"scope"` // system|cpuN|socketN|nodeN
+	Confidence     float64 `json:"confidence"`
+	LowConfidence  bool    `json:"low_confidence,omitempty"`
+	Ts             int64   `json:"ts_unix_ms"`
+	Source         string  `json:"source"` // perf
 }
 
 func NowMS() int64 { return time.Now().UnixMilli() }